@@ -37,6 +37,7 @@ func (g *Game) Update() error {
 	for _, l := range g.lights {
 		l.process(g.DeltaTime)
 	}
+	g.Display.Update(g.DeltaTime)
 
 	return nil
 }
@@ -81,10 +82,10 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.Display.Screen.Pixels[x+(y+1)*g.Display.Screen.Width] = 4
 	}
 
-	screen.ReplacePixels(g.Display.Screen.ToRGBA(display.ToRGBAOpts{
-		Pixels:  g.Display.RGBA,
-		Palette: g.Display.Palette,
-	}))
+	if err := g.Display.UpdateRGBA(); err != nil {
+		log.Fatal(err)
+	}
+	screen.ReplacePixels(g.Display.RGBA)
 
 	ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %v, CPU: %v", int(ebiten.CurrentFPS()), runtime.NumCPU()))
 }
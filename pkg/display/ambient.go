@@ -0,0 +1,117 @@
+package display
+
+import "math"
+
+// AmbientKey is a keyframe of ambient light schedule.
+type AmbientKey struct {
+	// Time of day, e.g. hours.
+	Time float64 `yaml:"time"`
+
+	// Light set limits at this time.
+	MinScale  float64 `yaml:"minscale"`
+	MaxScale  float64 `yaml:"maxscale"`
+	MinOffset float64 `yaml:"minoffset"`
+	MaxOffset float64 `yaml:"maxoffset"`
+
+	// Name of palette (see Display.SetPalette) to switch to when schedule
+	// passes this key, optional.
+	Palette string `yaml:"palette"`
+}
+
+// AmbientSchedule interpolates LightSet limits between keyframes over
+// the day (e.g. dawn, noon, dusk and night). After the last key it
+// wraps to the first one.
+type AmbientSchedule struct {
+	// Keys sorted by Time.
+	Keys []AmbientKey `yaml:"keys"`
+
+	// Length of the day in units of key Time. Default is 24.
+	DayLength float64 `yaml:"daylength"`
+
+	// Duration of palette crossfade in seconds.
+	PaletteFade float64 `yaml:"palettefade"`
+
+	// Palette set by schedule.
+	palette string
+}
+
+// At returns light limits at time of day t and name of palette
+// of the latest key that has it.
+func (a *AmbientSchedule) At(t float64) AmbientKey {
+	n := len(a.Keys)
+	if n == 0 {
+		return AmbientKey{}
+	}
+	day := a.DayLength
+	if day <= 0 {
+		day = 24
+	}
+	t = math.Mod(t, day)
+	if t < 0 {
+		t += day
+	}
+
+	// Key at or before t, wrapping to the last one.
+	i := n - 1
+	for k := range a.Keys {
+		if a.Keys[k].Time <= t {
+			i = k
+		}
+	}
+	k0, k1 := a.Keys[i], a.Keys[(i+1)%n]
+	span := math.Mod(k1.Time-k0.Time+day, day)
+	var f float64
+	if span > 0 {
+		f = math.Mod(t-k0.Time+day, day) / span
+	}
+	lerp := func(a, b float64) float64 {
+		return a + (b-a)*f
+	}
+	key := AmbientKey{
+		Time:      t,
+		MinScale:  lerp(k0.MinScale, k1.MinScale),
+		MaxScale:  lerp(k0.MaxScale, k1.MaxScale),
+		MinOffset: lerp(k0.MinOffset, k1.MinOffset),
+		MaxOffset: lerp(k0.MaxOffset, k1.MaxOffset),
+	}
+	for k := 0; k < n; k++ {
+		if p := a.Keys[(i-k+n)%n].Palette; p != "" {
+			key.Palette = p
+			break
+		}
+	}
+	return key
+}
+
+// Apply sets light set limits for time of day t.
+func (a *AmbientSchedule) Apply(l *LightSet, t float64) {
+	key := a.At(t)
+	l.MinScale = key.MinScale
+	l.MaxScale = key.MaxScale
+	l.MinOffset = key.MinOffset
+	l.MaxOffset = key.MaxOffset
+}
+
+// SetTimeOfDay applies Ambient schedule for time of day t to Lights (must
+// be *LightSet) and switches palette when schedule passes a key with
+// palette. Call it once per frame, not while drawing is in progress.
+func (d *Display) SetTimeOfDay(t float64) error {
+	a := d.Ambient
+	if a == nil {
+		return nil
+	}
+	if ls, ok := d.Lights.(*LightSet); ok {
+		a.Apply(ls, t)
+	}
+	p := a.At(t).Palette
+	if p == "" || p == a.palette {
+		return nil
+	}
+	fade := a.PaletteFade
+	if a.palette == "" {
+		// Don't fade on the first call.
+		fade = 0
+	}
+	a.palette = p
+	return d.SetPalette(p, fade)
+}
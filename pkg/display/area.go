@@ -0,0 +1,158 @@
+package display
+
+import "math"
+
+// DirectionalSource is a light that produces a linear gradient across
+// the screen, e.g. sun or moon light in outdoor scenes.
+// Sources in this file are not tied to objects. Change their fields
+// between frames to move them, set Removed to drop them from LightSet.
+type DirectionalSource struct {
+	// Point where light scale equals Intensity.
+	X, Y      float64
+	Intensity float64
+
+	// Direction in radians in which light grows by Gradient per pixel.
+	// 0 points along X axis, positive angles turn towards Y axis.
+	Angle    float64
+	Gradient float64
+
+	// Elevation of light above the screen in radians for normal mapping.
+	// Light comes from Angle direction. Default is Pi/4.
+	Elevation float64
+
+	Removed bool
+}
+
+// AddDirectional adds a directional light source.
+func (l *LightSet) AddDirectional(x, y, angle, intens, gradient float64) *DirectionalSource {
+	ds := &DirectionalSource{
+		X:         x,
+		Y:         y,
+		Intensity: intens,
+		Angle:     angle,
+		Gradient:  gradient,
+	}
+	l.add(ds)
+	return ds
+}
+
+func (s *DirectionalSource) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	sin, cos := math.Sincos(s.Angle)
+	d := (float64(posx)-s.X)*cos + (float64(posy)-s.Y)*sin
+	scale = s.Intensity + s.Gradient*d
+	if scale < 0 {
+		scale = 0
+	}
+	return 0, scale
+}
+
+func (s *DirectionalSource) Alive() bool {
+	return !s.Removed
+}
+
+// RectSource is an area light source of rectangle shape, e.g. a window.
+// Light is full inside the rectangle, outside it falls off with the distance
+// to the rectangle the same as CircleSource.
+type RectSource struct {
+	X, Y, W, H float64
+	Intensity  float64
+	FallRadius float64
+
+	// Attenuation curve. Default is FalloffDefault.
+	Falloff Falloff
+	// Light is exactly zero beyond this distance from the shape.
+	// 0 is unlimited.
+	MaxRadius float64
+
+	Removed bool
+}
+
+// AddRectLight adds a rectangle area light source with top-left corner
+// at (x, y).
+func (l *LightSet) AddRectLight(x, y, w, h, intens, rfall float64) *RectSource {
+	rs := &RectSource{
+		X:          x,
+		Y:          y,
+		W:          w,
+		H:          h,
+		Intensity:  intens,
+		FallRadius: rfall,
+	}
+	l.add(rs)
+	return rs
+}
+
+func (s *RectSource) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	dx := axisDistance(float64(posx), s.X, s.X+s.W)
+	dy := axisDistance(float64(posy), s.Y, s.Y+s.H)
+	fr2 := s.FallRadius * s.FallRadius
+	return 0, s.Intensity * attenuate(s.Falloff, dx*dx+dy*dy, fr2, s.MaxRadius)
+}
+
+func (s *RectSource) Alive() bool {
+	return !s.Removed
+}
+
+// axisDistance returns distance from v to the range min-max.
+func axisDistance(v, min, max float64) float64 {
+	switch {
+	case v < min:
+		return min - v
+	case v > max:
+		return v - max
+	}
+	return 0
+}
+
+// LineSource is an area light source of line segment shape,
+// e.g. a neon tube. Light falls off with the distance to the segment
+// the same as CircleSource.
+type LineSource struct {
+	X0, Y0, X1, Y1 float64
+	Intensity      float64
+	FallRadius     float64
+
+	// Attenuation curve. Default is FalloffDefault.
+	Falloff Falloff
+	// Light is exactly zero beyond this distance from the shape.
+	// 0 is unlimited.
+	MaxRadius float64
+
+	Removed bool
+}
+
+// AddLineLight adds a line segment area light source.
+func (l *LightSet) AddLineLight(x0, y0, x1, y1, intens, rfall float64) *LineSource {
+	ls := &LineSource{
+		X0:         x0,
+		Y0:         y0,
+		X1:         x1,
+		Y1:         y1,
+		Intensity:  intens,
+		FallRadius: rfall,
+	}
+	l.add(ls)
+	return ls
+}
+
+func (s *LineSource) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	px, py := float64(posx)-s.X0, float64(posy)-s.Y0
+	vx, vy := s.X1-s.X0, s.Y1-s.Y0
+	// Projection of the point to the segment, 0-1.
+	var t float64
+	if l2 := vx*vx + vy*vy; l2 > 0 {
+		t = (px*vx + py*vy) / l2
+		if t < 0 {
+			t = 0
+		} else if t > 1 {
+			t = 1
+		}
+	}
+	dx, dy := px-vx*t, py-vy*t
+	fr2 := s.FallRadius * s.FallRadius
+	return 0, s.Intensity * attenuate(s.Falloff, dx*dx+dy*dy, fr2, s.MaxRadius)
+}
+
+func (s *LineSource) Alive() bool {
+	return !s.Removed
+}
@@ -67,9 +67,11 @@ func TriShaderIndexed(iim IndexedImage, tx0, ty0, tx1, ty1, tx2, ty2 int,
 		if c == 0 {
 			return
 		}
-		in := o.Lights.Light(c, int(o.X), int(o.Y))
-		col := o.Indexizer.Indexize(in, int(o.X), int(o.Y))
-		o.Buffer[o.BufferOffset] = col
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, shade(o.Lights, o.Indexizer, c, x, y), x, y)
 	}
 }
 
@@ -90,9 +92,11 @@ func PerspTriShaderIndexed(iim IndexedImage, tx0, ty0, tx1, ty1, tx2, ty2 int,
 		if c == 0 {
 			return
 		}
-		in := o.Lights.Light(c, int(o.X), int(o.Y))
-		col := o.Indexizer.Indexize(in, int(o.X), int(o.Y))
-		o.Buffer[o.BufferOffset] = col
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, shade(o.Lights, o.Indexizer, c, x, y), x, y)
 	}
 }
 
@@ -107,8 +111,35 @@ func RectShaderIndexed(iim IndexedImage, tx0, ty0, tx1, ty1 int) func(o *Rectang
 		if c == 0 {
 			return
 		}
-		in := o.Lights.Light(c, int(o.X), int(o.Y))
-		col := o.Indexizer.Indexize(in, int(o.X), int(o.Y))
-		o.Buffer[o.BufferOffset] = col
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, shade(o.Lights, o.Indexizer, c, x, y), x, y)
+	}
+}
+
+// RectShaderUnlit draws image pixels as color indices bypassing lights and
+// indexizer. Remap is applied to colors when set.
+// Use it for images made with QuantizeImage.
+func RectShaderUnlit(iim IndexedImage, tx0, ty0, tx1, ty1 int, remap *Remap) func(o *RectangleShaderOpts) {
+	tw := float64(tx1-tx0) + 1
+	th := float64(ty1-ty0) + 1
+
+	return func(o *RectangleShaderOpts) {
+		cx := int(float64(tw*o.Px)) + tx0
+		cy := int(float64(th*o.Py)) + ty0
+		c := iim.Pixels[cx+cy*iim.Width]
+		if remap != nil {
+			c = remap[c]
+		}
+		if c == 0 {
+			return
+		}
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, c, x, y)
 	}
 }
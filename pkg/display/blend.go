@@ -0,0 +1,232 @@
+package display
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// BlendMode defines how a drawn pixel is combined with the pixel
+// that is already in the buffer.
+type BlendMode int
+
+const (
+	// BlendNormal replaces buffer pixel.
+	BlendNormal BlendMode = iota
+	// BlendAdd shifts buffer pixel index up by (color - 1), so the color 1
+	// (darkest) leaves the pixel intact. Empty pixels are replaced.
+	BlendAdd
+	// BlendDarken shifts buffer pixel index down by (MaxIndex - color),
+	// so the brightest color leaves the pixel intact. Empty pixels are
+	// left empty.
+	BlendDarken
+)
+
+// DitherMode defines thresholds used for screen-door transparency.
+type DitherMode int
+
+const (
+	// DitherOrdered uses 8x8 Bayer matrix. Produces regular pattern.
+	DitherOrdered DitherMode = iota
+	// DitherBlueNoise uses 16x16 blue noise matrix. Produces less
+	// noticeable irregular pattern.
+	DitherBlueNoise
+)
+
+// Blend is a structure with options of combining drawn pixels with
+// the buffer. Indexed colors can't be alpha-blended, so transparency
+// is implemented as screen-door: a part of pixels is not drawn according
+// to threshold matrix.
+// Zero value draws opaque pixels replacing buffer content.
+type Blend struct {
+	Mode BlendMode
+
+	// Transparency in the range 0 (opaque) to 1 (invisible).
+	Transparency float64
+
+	// Threshold matrix for transparency.
+	Dither DitherMode
+
+	// Maximum color index for BlendAdd and BlendDarken (typically number
+	// of colors in palette). Default is 255.
+	MaxIndex byte
+
+	// Counter of written pixels for statistics.
+	written *int
+}
+
+// Visible checks whether pixel at given point is drawn with current
+// transparency. Call it before heavy calculations of pixel color.
+func (b *Blend) Visible(posx, posy int) bool {
+	if b.Transparency <= 0 {
+		return true
+	}
+	if b.Transparency >= 1 {
+		return false
+	}
+	var th float64
+	switch b.Dither {
+	case DitherBlueNoise:
+		th = blueNoiseThreshold(posx, posy)
+	default:
+		th = bayerThreshold(posx, posy)
+	}
+	return th >= b.Transparency
+}
+
+// Put writes color to the buffer at offset according to blend mode.
+// Visibility is not checked, see Visible.
+func (b *Blend) Put(buf []byte, offs int, col byte, posx, posy int) {
+	switch b.Mode {
+	case BlendAdd:
+		dst := buf[offs]
+		if dst != 0 {
+			col = clampIndex(int(dst)+int(col)-1, b.maxIndex())
+		}
+	case BlendDarken:
+		dst := buf[offs]
+		if dst == 0 {
+			// Nothing to darken.
+			return
+		}
+		col = clampIndex(int(dst)-int(b.maxIndex())+int(col), b.maxIndex())
+	}
+	buf[offs] = col
+	if b.written != nil {
+		*b.written++
+	}
+}
+
+func (b *Blend) maxIndex() byte {
+	if b.MaxIndex == 0 {
+		return 255
+	}
+	return b.MaxIndex
+}
+
+// clampIndex clamps color index to the range 1-max.
+func clampIndex(v int, max byte) byte {
+	if v < 1 {
+		return 1
+	}
+	if v > int(max) {
+		return max
+	}
+	return byte(v)
+}
+
+var bayer8 = [64]byte{
+	0, 32, 8, 40, 2, 34, 10, 42,
+	48, 16, 56, 24, 50, 18, 58, 26,
+	12, 44, 4, 36, 14, 46, 6, 38,
+	60, 28, 52, 20, 62, 30, 54, 22,
+	3, 35, 11, 43, 1, 33, 9, 41,
+	51, 19, 59, 27, 49, 17, 57, 25,
+	15, 47, 7, 39, 13, 45, 5, 37,
+	63, 31, 55, 23, 61, 29, 53, 21,
+}
+
+// bayerThreshold returns ordered dither threshold (0-1) at the point.
+func bayerThreshold(posx, posy int) float64 {
+	return (float64(bayer8[posx&7+(posy&7)*8]) + 0.5) / 64
+}
+
+const blueNoiseBits = 4
+const blueNoiseSize = 1 << blueNoiseBits
+const blueNoiseMask = blueNoiseSize - 1
+
+var (
+	blueNoise     [blueNoiseSize * blueNoiseSize]float64
+	blueNoiseOnce sync.Once
+)
+
+// blueNoiseThreshold returns blue noise dither threshold (0-1) at the point.
+func blueNoiseThreshold(posx, posy int) float64 {
+	blueNoiseOnce.Do(initBlueNoise)
+	return blueNoise[posx&blueNoiseMask+(posy&blueNoiseMask)*blueNoiseSize]
+}
+
+// initBlueNoise generates blue noise threshold matrix with
+// void-and-cluster method.
+func initBlueNoise() {
+	const n = blueNoiseSize * blueNoiseSize
+	const sigma = 1.5
+
+	// Gaussian energy filter for every offset on the torus.
+	var filter [n]float64
+	for y := 0; y < blueNoiseSize; y++ {
+		for x := 0; x < blueNoiseSize; x++ {
+			dx := math.Min(float64(x), float64(blueNoiseSize-x))
+			dy := math.Min(float64(y), float64(blueNoiseSize-y))
+			filter[x+y*blueNoiseSize] = math.Exp(-(dx*dx + dy*dy) / (2 * sigma * sigma))
+		}
+	}
+
+	var pattern [n]bool
+	var energy [n]float64
+	update := func(p int, sign float64) {
+		px, py := p&blueNoiseMask, p>>blueNoiseBits
+		for i := range energy {
+			dx := (i&blueNoiseMask - px) & blueNoiseMask
+			dy := (i>>blueNoiseBits - py) & blueNoiseMask
+			energy[i] += sign * filter[dx+dy*blueNoiseSize]
+		}
+	}
+	// Finds the tightest cluster (set pixel with max energy) or the largest
+	// void (unset pixel with min energy).
+	find := func(set bool) int {
+		best := -1
+		for i := range energy {
+			if pattern[i] != set {
+				continue
+			}
+			if best < 0 || (set && energy[i] > energy[best]) || (!set && energy[i] < energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	// Initial random pattern, deterministic.
+	rnd := rand.New(rand.NewSource(1))
+	ones := n / 10
+	for _, p := range rnd.Perm(n)[:ones] {
+		pattern[p] = true
+		update(p, 1)
+	}
+	// Relax pattern by moving points from clusters to voids.
+	for i := 0; i < n; i++ {
+		c := find(true)
+		pattern[c] = false
+		update(c, -1)
+		v := find(false)
+		pattern[v] = true
+		update(v, 1)
+		if v == c {
+			break
+		}
+	}
+
+	var rank [n]int
+	proto, protoEnergy := pattern, energy
+
+	// Rank initial points by removing the tightest clusters.
+	for r := ones - 1; r >= 0; r-- {
+		c := find(true)
+		pattern[c] = false
+		update(c, -1)
+		rank[c] = r
+	}
+	// Rank the rest by filling the largest voids.
+	pattern, energy = proto, protoEnergy
+	for r := ones; r < n; r++ {
+		v := find(false)
+		pattern[v] = true
+		update(v, 1)
+		rank[v] = r
+	}
+
+	for i, r := range rank {
+		blueNoise[i] = (float64(r) + 0.5) / n
+	}
+}
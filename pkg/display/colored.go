@@ -0,0 +1,181 @@
+package display
+
+// RampLights is an optional interface of Lights that supports colored
+// lights. Besides intensity it returns palette ramp of the light that
+// dominates at the point (0 for default ramp). Normal n is optional.
+type RampLights interface {
+	Lights
+	LightRamp(val byte, n *Normal, posx, posy int) (intens float64, ramp int)
+}
+
+// RampIndexizer is an optional interface of Indexizer that picks colors
+// from the palette ramp chosen by colored lights.
+type RampIndexizer interface {
+	Indexizer
+	IndexizeRamp(intens float64, ramp int, posx, posy int) byte
+}
+
+// RampSource is an optional interface of LightSource that has color
+// (palette ramp).
+type RampSource interface {
+	LightSource
+	LightRamp() int
+}
+
+// Tinted is a colored light source. Pixels where it provides more
+// than half of light scale (ambient MinScale included) take colors
+// from its ramp, see Ramps.
+type Tinted struct {
+	Source LightSource
+	// Ramp number, starting from 1.
+	Ramp int
+}
+
+// Tint wraps the light source with the ramp and adds it to the set.
+func (l *LightSet) Tint(s LightSource, ramp int) *Tinted {
+	t := &Tinted{
+		Source: s,
+		Ramp:   ramp,
+	}
+	l.add(t)
+	return t
+}
+
+func (t *Tinted) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	return t.Source.OffsetScale(posx, posy)
+}
+
+func (t *Tinted) Alive() bool {
+	return t.Source.Alive()
+}
+
+func (t *Tinted) LightRamp() int {
+	return t.Ramp
+}
+
+func (t *Tinted) Unwrap() LightSource {
+	return t.Source
+}
+
+func (t *Tinted) SetTime(tm float64) {
+	if as, ok := t.Source.(AnimatedSource); ok {
+		as.SetTime(tm)
+	}
+}
+
+func (t *Tinted) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	if b, ok := t.Source.(BoundedSource); ok {
+		return b.Bounds()
+	}
+	return 0, 0, 0, 0, false
+}
+
+// unwrapSource unwraps source wrappers (e.g. Modulated, Tinted) until
+// match returns true. Returns the innermost source if nothing matches.
+func unwrapSource(s LightSource, match func(LightSource) bool) LightSource {
+	for !match(s) {
+		w, ok := s.(interface{ Unwrap() LightSource })
+		if !ok {
+			break
+		}
+		s = w.Unwrap()
+	}
+	return s
+}
+
+// sourceRamp returns ramp of the source or 0 if it's not colored.
+func sourceRamp(s LightSource) int {
+	s = unwrapSource(s, func(s LightSource) bool {
+		_, ok := s.(RampSource)
+		return ok
+	})
+	if rs, ok := s.(RampSource); ok {
+		return rs.LightRamp()
+	}
+	return 0
+}
+
+func (l *LightSet) LightRamp(val byte, n *Normal, posx, posy int) (intens float64, ramp int) {
+	return l.eval(l.Sources, val, n, true, posx, posy)
+}
+
+func (c *cellLights) LightRamp(val byte, n *Normal, posx, posy int) (intens float64, ramp int) {
+	return c.set.eval(c.sources, val, n, true, posx, posy)
+}
+
+func (l *countingLights) LightRamp(val byte, n *Normal, posx, posy int) (intens float64, ramp int) {
+	l.evals++
+	if rl, ok := l.Lights.(RampLights); ok {
+		return rl.LightRamp(val, n, posx, posy)
+	}
+	if n != nil {
+		return lightNormal(l.Lights, val, *n, posx, posy), 0
+	}
+	return l.Lights.Light(val, posx, posy), 0
+}
+
+// rampShader passes ramp of colored lights from Light to the following
+// Indexize call. It's both lights and indexizer of a single chunk,
+// so it's never used concurrently.
+type rampShader struct {
+	lights RampLights
+	ind    RampIndexizer
+	ramp   int
+}
+
+// rampShading returns lights and indexizer that shade with colored lights
+// when both support ramps, or l and ind as is. Call it once per chunk:
+// optional interfaces are not checked for each pixel then.
+func rampShading(l Lights, ind Indexizer) (Lights, Indexizer) {
+	ri, ok := ind.(RampIndexizer)
+	if !ok {
+		return l, ind
+	}
+	rl, ok := l.(RampLights)
+	if !ok {
+		return l, ind
+	}
+	rs := &rampShader{lights: rl, ind: ri}
+	return rs, rs
+}
+
+func (s *rampShader) Light(val byte, posx, posy int) float64 {
+	var intens float64
+	intens, s.ramp = s.lights.LightRamp(val, nil, posx, posy)
+	return intens
+}
+
+func (s *rampShader) LightNormal(val byte, n Normal, posx, posy int) float64 {
+	var intens float64
+	intens, s.ramp = s.lights.LightRamp(val, &n, posx, posy)
+	return intens
+}
+
+// Indexize uses ramp of the last Light call. Without it (e.g. for emissive
+// pixels) default ramp is used.
+func (s *rampShader) Indexize(intens float64, posx, posy int) byte {
+	ramp := s.ramp
+	s.ramp = 0
+	return s.ind.IndexizeRamp(intens, ramp, posx, posy)
+}
+
+// Ramps is an indexizer for colored lights. Default ramp (0) is indexized
+// by Indexizer. For ramp i its result is remapped by Remaps[i-1], e.g. from
+// grey colors to the band of red colors of the palette. Unknown ramps are
+// treated as default.
+type Ramps struct {
+	Indexizer Indexizer
+	Remaps    []*Remap
+}
+
+func (r *Ramps) Indexize(intens float64, posx, posy int) byte {
+	return r.Indexizer.Indexize(intens, posx, posy)
+}
+
+func (r *Ramps) IndexizeRamp(intens float64, ramp int, posx, posy int) byte {
+	c := r.Indexizer.Indexize(intens, posx, posy)
+	if ramp > 0 && ramp <= len(r.Remaps) && r.Remaps[ramp-1] != nil {
+		return r.Remaps[ramp-1][c]
+	}
+	return c
+}
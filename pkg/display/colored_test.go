@@ -0,0 +1,57 @@
+package display
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestColoredLights(t *testing.T) {
+	for _, workers := range []int{0, 3} {
+		t.Run(fmt.Sprintf("workers=%v", workers), func(t *testing.T) {
+			d := newTestDisplay(workers)
+			defer d.Close()
+
+			// Solid sprite, so every pixel is shaded with the same value.
+			atlas := IndexedImage{Width: 4, Height: 4, Pixels: make([]byte, 16)}
+			for i := range atlas.Pixels {
+				atlas.Pixels[i] = 200
+			}
+			d.Sprites["solid"] = &Sprite{Atlas: &atlas, Width: 2, Height: 2}
+
+			ls := &LightSet{MinScale: 0.3, MaxScale: 2, MaxOffset: 1}
+			ls.TrackCircle(testTracked{120, 80}, 1, 20)
+			ls.Tint(&CircleSource{Tracked: testTracked{40, 40}, Intensity: 1, FallRadius: 10, MaxRadius: 30}, 1)
+			ls.UpdateGrid()
+			red := NewRemap()
+			for i := 1; i <= 4; i++ {
+				red[i] = byte(100 + i)
+			}
+			ramps := &Ramps{Indexizer: Bits2, Remaps: []*Remap{red}}
+			d.Lights, d.Indexizer = ls, ramps
+
+			d.DrawSpriteAdvanced(DrawSpriteOpts{
+				Name: "solid",
+				DW:   float64(d.Screen.Width),
+				DH:   float64(d.Screen.Height),
+			})
+
+			var tinted int
+			for y := 0; y < d.Screen.Height-1; y++ {
+				for x := 0; x < d.Screen.Width-1; x++ {
+					in, ramp := ls.LightRamp(200, nil, x, y)
+					want := ramps.IndexizeRamp(in, ramp, x, y)
+					got := d.Screen.Pixels[x+y*d.Screen.Width]
+					if got != want {
+						t.Fatalf("(%v, %v): got color %v, want %v", x, y, got, want)
+					}
+					if got > 100 {
+						tinted++
+					}
+				}
+			}
+			if tinted == 0 || tinted > d.Screen.Width*d.Screen.Height/4 {
+				t.Errorf("got %v tinted pixels", tinted)
+			}
+		})
+	}
+}
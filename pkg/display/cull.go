@@ -0,0 +1,203 @@
+package display
+
+import "math"
+
+// ChunkLights is an optional interface of Lights that provides lights model
+// limited to screen rectangle, which is cheaper to evaluate.
+// Rasterizer calls it once for each chunk.
+type ChunkLights interface {
+	Lights
+	ChunkLights(x, y, w, h int) Lights
+}
+
+// BoundedSource is an optional interface of LightSource which light is
+// zero outside of bounds rectangle. ok is false if source is not bounded
+// (e.g. MaxRadius is not set).
+type BoundedSource interface {
+	LightSource
+	Bounds() (x0, y0, x1, y1 float64, ok bool)
+}
+
+// Maximum number of culling grid cells. Grid is not used if bounded
+// sources cover larger area.
+const maxGridCells = 1 << 16
+
+// Maximum number of grid cells covered by a single source. Larger
+// sources are evaluated everywhere, like unbounded ones.
+const maxSourceCells = 1 << 12
+
+// lightGrid is a per-frame spatial grid of light sources.
+type lightGrid struct {
+	bits int
+	// Number of sources and their generation when grid was built.
+	sources int
+	gen     int
+
+	// Grid origin and size in cells.
+	cx, cy     int
+	cols, rows int
+
+	cells []cellLights
+	// Lights outside the grid: unbounded and oversized sources.
+	outside cellLights
+}
+
+// cellLights is a lights model of a grid cell.
+type cellLights struct {
+	set     *LightSet
+	sources []LightSource
+}
+
+func (c *cellLights) Light(val byte, posx, posy int) float64 {
+	return c.set.light(c.sources, val, posx, posy)
+}
+
+// UpdateGrid builds light culling grid. Call it once per frame after moving
+// light sources and before drawing. Then for each rasterizer chunk only the
+// sources whose bounds overlap the chunk are evaluated. Sources without bounds
+// (see BoundedSource) or with very large bounds are evaluated everywhere.
+// Until the next call, grid is used only while sources are not added or
+// removed (by set methods, or so that their number changes). Call it again
+// after replacing sources in Sources directly. Don't call it while drawing
+// is in progress.
+func (l *LightSet) UpdateGrid() {
+	l.grid = nil
+	bits := l.GridBits
+	if bits == 0 {
+		bits = 5
+	}
+
+	g := &lightGrid{
+		bits:    bits,
+		sources: len(l.Sources),
+		gen:     l.gen,
+		outside: cellLights{set: l},
+	}
+	type bounded struct {
+		s              LightSource
+		x0, y0, x1, y1 int
+	}
+	var bs []bounded
+	minX, minY := math.MaxInt32, math.MaxInt32
+	maxX, maxY := math.MinInt32, math.MinInt32
+	cell := float64(int(1) << bits)
+	for _, s := range l.Sources {
+		if b, ok := s.(BoundedSource); ok {
+			x0, y0, x1, y1, ok := b.Bounds()
+			// NaN and infinite bounds fail the check.
+			if ok && (x1-x0+cell)*(y1-y0+cell) <= maxSourceCells*cell*cell {
+				c := bounded{
+					s:  s,
+					x0: int(math.Floor(x0)) >> bits,
+					y0: int(math.Floor(y0)) >> bits,
+					x1: int(math.Floor(x1)) >> bits,
+					y1: int(math.Floor(y1)) >> bits,
+				}
+				bs = append(bs, c)
+				minX, minY = minInt(minX, c.x0), minInt(minY, c.y0)
+				maxX, maxY = maxInt(maxX, c.x1), maxInt(maxY, c.y1)
+				continue
+			}
+		}
+		g.outside.sources = append(g.outside.sources, s)
+	}
+	if len(bs) > 0 {
+		g.cx, g.cy = minX, minY
+		g.cols, g.rows = maxX-minX+1, maxY-minY+1
+		if g.cols*g.rows > maxGridCells {
+			return
+		}
+	}
+
+	g.cells = make([]cellLights, g.cols*g.rows)
+	for i := range g.cells {
+		g.cells[i].set = l
+		g.cells[i].sources = g.outside.sources[:len(g.outside.sources):len(g.outside.sources)]
+	}
+	for _, b := range bs {
+		for y := b.y0; y <= b.y1; y++ {
+			for x := b.x0; x <= b.x1; x++ {
+				c := &g.cells[x-g.cx+(y-g.cy)*g.cols]
+				c.sources = append(c.sources, b.s)
+			}
+		}
+	}
+	l.grid = g
+}
+
+// ChunkLights returns lights model with sources culled by the grid built
+// with UpdateGrid. Without grid, or if rectangle spans several grid cells,
+// the set itself is returned.
+func (l *LightSet) ChunkLights(x, y, w, h int) Lights {
+	g := l.grid
+	if g == nil || g.sources != len(l.Sources) || g.gen != l.gen || w <= 0 || h <= 0 {
+		return l
+	}
+	cx, cy := x>>g.bits, y>>g.bits
+	if (x+w-1)>>g.bits != cx || (y+h-1)>>g.bits != cy {
+		return l
+	}
+	cx -= g.cx
+	cy -= g.cy
+	if cx < 0 || cy < 0 || cx >= g.cols || cy >= g.rows {
+		return &g.outside
+	}
+	return &g.cells[cx+cy*g.cols]
+}
+
+// chunkLights returns lights model for the chunk rectangle.
+func chunkLights(l Lights, x, y, w, h float64) Lights {
+	if cl, ok := l.(ChunkLights); ok {
+		return cl.ChunkLights(int(x), int(y), int(math.Ceil(w)), int(math.Ceil(h)))
+	}
+	return l
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (s *CircleSource) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	if s.MaxRadius <= 0 {
+		return 0, 0, 0, 0, false
+	}
+	x, y := s.Tracked.Pos()
+	r := s.MaxRadius
+	return x - r, y - r, x + r, y + r, true
+}
+
+func (s *SpotSource) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	if s.MaxRadius <= 0 {
+		return 0, 0, 0, 0, false
+	}
+	x, y := s.Tracked.Pos()
+	r := s.MaxRadius
+	return x - r, y - r, x + r, y + r, true
+}
+
+func (s *RectSource) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	if s.MaxRadius <= 0 {
+		return 0, 0, 0, 0, false
+	}
+	r := s.MaxRadius
+	return s.X - r, s.Y - r, s.X + s.W + r, s.Y + s.H + r, true
+}
+
+func (s *LineSource) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	if s.MaxRadius <= 0 {
+		return 0, 0, 0, 0, false
+	}
+	r := s.MaxRadius
+	return math.Min(s.X0, s.X1) - r, math.Min(s.Y0, s.Y1) - r,
+		math.Max(s.X0, s.X1) + r, math.Max(s.Y0, s.Y1) + r, true
+}
@@ -0,0 +1,55 @@
+package display
+
+import "testing"
+
+func TestUpdateGridOversizedSource(t *testing.T) {
+	l := &LightSet{MaxScale: 2, MaxOffset: 1}
+	l.Sources = append(l.Sources,
+		&CircleSource{Tracked: testTracked{40, 40}, Intensity: 1, FallRadius: 10, MaxRadius: 20},
+		&CircleSource{Tracked: testTracked{100, 50}, Intensity: 0.5, FallRadius: 1e5, MaxRadius: 1e6},
+		&CircleSource{Tracked: testTracked{0, 0}, Intensity: 0.5, FallRadius: 10},
+	)
+	l.UpdateGrid()
+	if l.grid == nil {
+		t.Fatal("grid is not built")
+	}
+	if n := len(l.grid.outside.sources); n != 2 {
+		t.Errorf("got %v sources outside grid, want 2", n)
+	}
+
+	for _, p := range [][2]int{{40, 40}, {50, 35}, {100, 50}, {500, 500}, {-300, 20}} {
+		cl := l.ChunkLights(p[0], p[1], 1, 1)
+		if _, ok := cl.(*cellLights); !ok {
+			t.Errorf("%v: got %T, want cell lights", p, cl)
+		}
+		got, want := cl.Light(100, p[0], p[1]), l.Light(100, p[0], p[1])
+		if got != want {
+			t.Errorf("%v: got light %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestUpdateGridStale(t *testing.T) {
+	d := newTestDisplay(0)
+	ls := d.Lights.(*LightSet)
+	spr := *d.Sprites["s"]
+	spr.Glow = &Glow{Intensity: 1, FallRadius: 10, MaxRadius: 20}
+	d.Sprites["glow"] = &spr
+
+	d.DrawSprite("glow", 20, 20)
+	d.Update(0)
+	ls.UpdateGrid()
+	if _, ok := ls.ChunkLights(20, 20, 1, 1).(*cellLights); !ok {
+		t.Fatal("grid is not used")
+	}
+
+	// Glow is replaced, the number of sources is the same.
+	d.DrawSprite("glow", 150, 100)
+	d.Update(0)
+	for _, p := range [][2]int{{20, 20}, {150, 100}} {
+		got, want := ls.ChunkLights(p[0], p[1], 1, 1).Light(100, p[0], p[1]), ls.Light(100, p[0], p[1])
+		if got != want {
+			t.Errorf("%v: got light %v from stale grid, want %v", p, got, want)
+		}
+	}
+}
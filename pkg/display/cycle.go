@@ -0,0 +1,154 @@
+package display
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColorCycle rotates a range of palette colors over time.
+type ColorCycle struct {
+	// Range of color indices (both inclusive), starting from 1.
+	From int `yaml:"from"`
+	To   int `yaml:"to"`
+
+	// Speed, colors per second.
+	Speed float64 `yaml:"speed"`
+
+	// Rotate colors towards lower indices.
+	Reverse bool `yaml:"reverse"`
+
+	// Blend adjacent colors for smooth rotation. Without blending
+	// colors change in steps.
+	Blend bool `yaml:"blend"`
+}
+
+// PaletteCycler applies color cycles to a palette. Cycling is applied
+// when converting to RGBA, so the indexed image doesn't need redrawing.
+type PaletteCycler struct {
+	Cycles []ColorCycle
+
+	time float64
+}
+
+// Update advances cycler time by dt seconds.
+func (c *PaletteCycler) Update(dt float64) {
+	c.time += dt
+}
+
+// Reset sets cycler time to 0.
+func (c *PaletteCycler) Reset() {
+	c.time = 0
+}
+
+// Apply writes src palette with cycled colors to dst and returns it.
+// dst is reused if it has enough capacity.
+func (c *PaletteCycler) Apply(dst, src Palette) Palette {
+	dst = append(dst[:0], src...)
+	for _, cc := range c.Cycles {
+		cc.apply(dst, src, c.time)
+	}
+	return dst
+}
+
+func (cc *ColorCycle) apply(dst, src Palette, t float64) {
+	from, to := cc.From, cc.To
+	if from < 1 {
+		from = 1
+	}
+	if to > src.ColorsNumber() {
+		to = src.ColorsNumber()
+	}
+	n := to - from + 1
+	if n < 2 {
+		return
+	}
+	phase := t * cc.Speed
+	if cc.Reverse {
+		phase = -phase
+	}
+	steps := math.Floor(phase)
+	frac := phase - steps
+	shift := int(math.Mod(steps, float64(n)))
+	if shift < 0 {
+		shift += n
+	}
+
+	for i := 0; i < n; i++ {
+		// Colors move towards higher indices: color at i comes from i - shift.
+		a := (from - 1 + (i-shift+n)%n) * 3
+		d := (from - 1 + i) * 3
+		if !cc.Blend || frac == 0 {
+			copy(dst[d:d+3], src[a:a+3])
+			continue
+		}
+		b := (from - 1 + (i-shift-1+2*n)%n) * 3
+		for k := 0; k < 3; k++ {
+			v := float64(src[a+k])*(1-frac) + float64(src[b+k])*frac
+			dst[d+k] = byte(v + 0.5)
+		}
+	}
+}
+
+type paletteYaml struct {
+	Name   string       `yaml:"name"`
+	Colors []string     `yaml:"colors"`
+	Cycles []ColorCycle `yaml:"cycles"`
+}
+
+// LoadPalette loads palette with optional color cycles from YAML file and
+// stores them to Palettes and PaletteCycles under the name from file (or
+// file name without extension).
+// Colors are hex strings: "#rrggbb" or "rrggbb".
+func (d *Display) LoadPalette(fileName string) error {
+	if d.Palettes == nil {
+		d.Palettes = make(map[string]Palette)
+	}
+	if d.PaletteCycles == nil {
+		d.PaletteCycles = make(map[string][]ColorCycle)
+	}
+	pl, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	var py paletteYaml
+	err = yaml.Unmarshal(pl, &py)
+	if err != nil {
+		return err
+	}
+
+	pal := make(Palette, 0, len(py.Colors)*3)
+	for _, c := range py.Colors {
+		rgb, err := parseColor(c)
+		if err != nil {
+			return fmt.Errorf("palette %v: %v", fileName, err)
+		}
+		pal = append(pal, rgb[:]...)
+	}
+
+	if py.Name == "" {
+		p1 := strings.Split(strings.ReplaceAll(fileName, `\`, `/`), "/")
+		p2 := strings.Split(p1[len(p1)-1], ".")
+		py.Name = p2[0]
+	}
+	d.Palettes[py.Name] = pal
+	d.PaletteCycles[py.Name] = py.Cycles
+	return nil
+}
+
+// parseColor parses hex color "#rrggbb" or "rrggbb".
+func parseColor(s string) ([3]byte, error) {
+	h := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(h) != 6 {
+		return [3]byte{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return [3]byte{}, fmt.Errorf("invalid color %q", s)
+	}
+	return [3]byte{byte(v >> 16), byte(v >> 8), byte(v)}, nil
+}
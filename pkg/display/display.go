@@ -10,7 +10,39 @@ type Display struct {
 	Lights     Lights
 	Indexizer  Indexizer
 
+	// Integer upscale factor of RGBA buffer relative to Screen.
+	// Default is 1.
+	Scale int
+
+	// Palettes and their color cycles loaded with LoadPalette.
+	Palettes      map[string]Palette
+	PaletteCycles map[string][]ColorCycle
+
+	// Color cycler applied to Palette when converting to RGBA.
+	Cycler *PaletteCycler
+
+	// Remap tables loaded with LoadRemaps.
+	Remaps map[string]*Remap
+
+	// Ambient light schedule, see SetTimeOfDay.
+	Ambient *AmbientSchedule
+
+	// Function to call when drawing fails (e.g. display is misconfigured).
+	// By default each distinct error is logged once.
+	ErrorHandler func(err error)
+
 	reportedSprite map[string]struct{}
+	reportedError  map[string]struct{}
+
+	// Lookup table for the palette it was calculated for.
+	lut        ColorLUT
+	lutPalette Palette
+
+	// Palette with effects applied.
+	effPalette Palette
+	paletteFX  paletteEffects
+
+	glows glowLights
 }
 
 func (d *Display) InitBuffers(w, h int) {
@@ -19,5 +51,29 @@ func (d *Display) InitBuffers(w, h int) {
 		Height: h,
 		Pixels: make([]byte, w*h),
 	}
-	d.RGBA = make([]byte, w*h*4)
+	scale := d.Scale
+	if scale < 1 {
+		scale = 1
+	}
+	d.RGBA = make([]byte, w*h*4*scale*scale)
+}
+
+// Close stops rasterizer workers. Display can still be used after Close,
+// rasterizer is restarted on the next drawing call.
+func (d *Display) Close() {
+	d.Rasterizer.Close()
+}
+
+// Update advances palette effects (color cycling, transitions and fades)
+// and lights (when Lights is *LightSet) by dt seconds and updates lights
+// of glowing sprites. Call it once per frame, not while drawing is in progress.
+func (d *Display) Update(dt float64) {
+	if d.Cycler != nil {
+		d.Cycler.Update(dt)
+	}
+	d.paletteFX.update(dt)
+	d.updateGlows()
+	if ls, ok := d.Lights.(*LightSet); ok {
+		ls.Update(dt)
+	}
 }
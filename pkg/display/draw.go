@@ -1,5 +1,10 @@
 package display
 
+import (
+	"context"
+	"fmt"
+)
+
 type DrawSpriteOpts struct {
 	Name string
 	SX   float64
@@ -10,6 +15,18 @@ type DrawSpriteOpts struct {
 	DY   float64
 	DH   float64
 	DW   float64
+
+	// Transparency and blend mode. Zero value draws opaque sprite.
+	// MaxIndex defaults to the number of colors in Display palette.
+	Blend Blend
+
+	// Remap table applied to sprite colors after indexizing.
+	Remap *Remap
+	// Name of remap table in Display.Remaps. Used when Remap is not set.
+	RemapName string
+
+	// Draw sprite at full intensity regardless of lights.
+	Unlit bool
 }
 
 func (d *Display) DrawSprite(name string, x, y float64) {
@@ -42,27 +59,69 @@ func (d *Display) DrawSpriteAdvanced(o DrawSpriteOpts) {
 }
 
 func (d *Display) drawSpriteAdvanced(s *Sprite, o DrawSpriteOpts) {
+	ind := d.Indexizer
+	if o.Remap == nil && o.RemapName != "" {
+		o.Remap = d.Remaps[o.RemapName]
+		if o.Remap == nil {
+			d.reportError(fmt.Errorf("remap %v is not loaded", o.RemapName))
+		}
+	}
+	if o.Remap != nil && ind != nil {
+		ind = RemapIndexizer{Indexizer: ind, Remap: o.Remap}
+	}
+
+	tx0, ty0 := s.X+int(o.SX), s.Y+int(o.SY)
+	tx1, ty1 := tx0+int(o.SW), ty0+int(o.SH)
+	shader := RectShaderIndexed(*s.Atlas, tx0, ty0, tx1, ty1)
+	switch {
+	case s.Emissive != nil:
+		shader = RectShaderEmissive(*s.Atlas, *s.Emissive, s.Normals, tx0, ty0, tx1, ty1)
+	case s.Normals != nil:
+		shader = RectShaderNormal(*s.Atlas, s.Normals, tx0, ty0, tx1, ty1)
+	}
+	lights := d.Lights
+	if o.Unlit {
+		shader = RectShaderIndexed(*s.Atlas, tx0, ty0, tx1, ty1)
+		lights = FullLight
+	}
+	if s.Unlit {
+		shader = RectShaderUnlit(*s.Atlas, tx0, ty0, tx1, ty1, o.Remap)
+		// Unlit shader doesn't use them, but rasterizer requires both.
+		if lights == nil {
+			lights = FullLight
+		}
+		if ind == nil {
+			ind = Bits2
+		}
+	}
+
 	ri := RectangleInfo{
 		RectangleRasterInput: RectangleRasterInput{
 			Buffer:       d.Screen.Pixels,
 			BufferWidth:  d.Screen.Width,
 			BufferHeight: d.Screen.Height,
-			Shader: RectShaderIndexed(
-				*s.Atlas,
-				s.X+int(o.SX),
-				s.Y+int(o.SY),
-				s.X+int(o.SX)+int(o.SW),
-				s.Y+int(o.SY)+int(o.SH),
-			),
-			Indexizer: d.Indexizer,
-			Lights:    d.Lights,
+			Shader:       shader,
+			Indexizer:    ind,
+			Lights:       lights,
+			Blend:        d.blend(o.Blend),
 		},
 		X: o.DX - float64(s.XOrigin),
 		Y: o.DY - float64(s.YOrigin),
 		W: o.DW,
 		H: o.DH,
 	}
-	d.Rasterizer.DrawRectangle(ri)
+	d.reportError(d.Rasterizer.DrawRectangleContext(context.Background(), ri))
+	if s.Glow != nil {
+		d.addGlow(s.Glow, o.DX, o.DY)
+	}
+}
+
+// blend fills blend defaults from the display.
+func (d *Display) blend(b Blend) Blend {
+	if b.MaxIndex == 0 && d.Palette.ColorsNumber() > 0 && d.Palette.ColorsNumber() < 256 {
+		b.MaxIndex = byte(d.Palette.ColorsNumber())
+	}
+	return b
 }
 
 // deprecated: 3 times slower vs drawSpriteAdvanced.
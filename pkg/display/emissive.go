@@ -0,0 +1,136 @@
+package display
+
+// Glow is a light emitted by sprite. When sprite with Glow is drawn,
+// a circle light source is added to Display.Lights (must be *LightSet)
+// at sprite position on the next Display.Update call. The light stays until
+// the following Update, so it lags one frame behind the sprite.
+// Up to 1024 glows are recorded between Update calls, the rest
+// are ignored.
+type Glow struct {
+	Intensity  float64 `yaml:"intensity"`
+	FallRadius float64 `yaml:"radius"`
+	MaxRadius  float64 `yaml:"maxradius"`
+
+	// Light position relative to sprite origin.
+	X float64 `yaml:"x"`
+	Y float64 `yaml:"y"`
+}
+
+// Maximum number of glows recorded between Display.Update calls.
+const maxGlows = 1024
+
+// glowPos is a position of glow light.
+type glowPos struct {
+	x, y float64
+}
+
+func (p glowPos) Pos() (float64, float64) { return p.x, p.y }
+func (p glowPos) Alive() bool             { return true }
+func (p glowPos) SizeMod() float64        { return 1 }
+
+// glowSource is a light source of glow with its position.
+type glowSource struct {
+	CircleSource
+	pos glowPos
+}
+
+// glowLights holds glow light sources of drawn sprites. Sources are
+// allocated in two pools that swap on Update and are reused between frames.
+type glowLights struct {
+	pools [2][]*glowSource
+	// Number of used sources in each pool.
+	used [2]int
+	// Pool of sprites drawn since the last Update. The other pool holds
+	// sources registered on LightSet.
+	cur int
+}
+
+// addGlow records glow of the sprite drawn at (x, y).
+func (d *Display) addGlow(g *Glow, x, y float64) {
+	if _, ok := d.Lights.(*LightSet); !ok {
+		return
+	}
+	gl := &d.glows
+	n := gl.used[gl.cur]
+	if n >= maxGlows {
+		return
+	}
+	if n == len(gl.pools[gl.cur]) {
+		gl.pools[gl.cur] = append(gl.pools[gl.cur], &glowSource{})
+	}
+	gs := gl.pools[gl.cur][n]
+	gs.pos = glowPos{x + g.X, y + g.Y}
+	// Tracked points to pos, so updating it doesn't allocate.
+	gs.CircleSource = CircleSource{
+		Tracked:    &gs.pos,
+		Intensity:  g.Intensity,
+		FallRadius: g.FallRadius,
+		MaxRadius:  g.MaxRadius,
+	}
+	gl.used[gl.cur]++
+}
+
+// updateGlows replaces glow lights on LightSet with ones of sprites drawn
+// since the last call.
+func (d *Display) updateGlows() {
+	ls, ok := d.Lights.(*LightSet)
+	gl := &d.glows
+	if !ok || gl.used[0]+gl.used[1] == 0 {
+		return
+	}
+	active := gl.pools[1-gl.cur][:gl.used[1-gl.cur]]
+	if len(active) > 0 {
+		old := make(map[LightSource]struct{}, len(active))
+		for _, s := range active {
+			old[&s.CircleSource] = struct{}{}
+		}
+		n := 0
+		for _, s := range ls.Sources {
+			if _, ok := old[s]; !ok {
+				ls.Sources[n] = s
+				n++
+			}
+		}
+		for i := n; i < len(ls.Sources); i++ {
+			ls.Sources[i] = nil
+		}
+		ls.Sources = ls.Sources[:n]
+	}
+	for _, s := range gl.pools[gl.cur][:gl.used[gl.cur]] {
+		ls.Sources = append(ls.Sources, &s.CircleSource)
+	}
+	ls.gen++
+	gl.cur = 1 - gl.cur
+	gl.used[gl.cur] = 0
+}
+
+// RectShaderEmissive is like RectShaderIndexed, but pixels that are set
+// in emissive mask (image of the same size) bypass lights and are drawn
+// at full intensity. Normal map nm is optional (see RectShaderNormal).
+func RectShaderEmissive(iim, emissive IndexedImage, nm *NormalMap, tx0, ty0, tx1, ty1 int) func(o *RectangleShaderOpts) {
+	tw := float64(tx1-tx0) + 1
+	th := float64(ty1-ty0) + 1
+
+	return func(o *RectangleShaderOpts) {
+		cx := int(float64(tw*o.Px)) + tx0
+		cy := int(float64(th*o.Py)) + ty0
+		c := iim.Pixels[cx+cy*iim.Width]
+		if c == 0 {
+			return
+		}
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		var col byte
+		switch {
+		case emissive.Pixels[cx+cy*emissive.Width] != 0:
+			col = o.Indexizer.Indexize(float64(c)/255, x, y)
+		case nm != nil:
+			col = shadeNormal(o.Lights, o.Indexizer, c, nm.Normals[cx+cy*nm.Width], x, y)
+		default:
+			col = shade(o.Lights, o.Indexizer, c, x, y)
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, col, x, y)
+	}
+}
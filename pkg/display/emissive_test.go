@@ -0,0 +1,51 @@
+package display
+
+import "testing"
+
+func TestGlowsReused(t *testing.T) {
+	d := newTestDisplay(0)
+	ls := d.Lights.(*LightSet)
+	spr := *d.Sprites["s"]
+	spr.Glow = &Glow{Intensity: 1, FallRadius: 10}
+	d.Sprites["glow"] = &spr
+	base := len(ls.Sources)
+
+	draw := func(n int) {
+		for i := 0; i < n; i++ {
+			d.DrawSprite("glow", float64(i), 10)
+		}
+	}
+
+	// Without Update glows are capped.
+	draw(maxGlows + 10)
+	if n := len(d.glows.pools[d.glows.cur]); n != maxGlows {
+		t.Fatalf("got %v pending glows, want %v", n, maxGlows)
+	}
+	d.Update(0)
+	if n := len(ls.Sources); n != base+maxGlows {
+		t.Fatalf("got %v sources, want %v", n, base+maxGlows)
+	}
+
+	for frame := 0; frame < 4; frame++ {
+		draw(3)
+		d.Update(0)
+		if n := len(ls.Sources); n != base+3 {
+			t.Fatalf("frame %v: got %v sources, want %v", frame, n, base+3)
+		}
+		for i, s := range ls.Sources[base:] {
+			if x, _ := s.(*CircleSource).Tracked.Pos(); x != float64(i) {
+				t.Errorf("frame %v: glow %v is at %v", frame, i, x)
+			}
+		}
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		d.glows.used[d.glows.cur] = 0
+		for i := 0; i < 3; i++ {
+			d.addGlow(spr.Glow, 0, 0)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("adding glow allocates %v times", allocs)
+	}
+}
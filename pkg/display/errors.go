@@ -0,0 +1,91 @@
+package display
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Errors of invalid drawing input. Drawing functions wrap them
+// into DrawError, use errors.Is to check.
+var (
+	ErrBufferNotSet    = errors.New("buffer is not set")
+	ErrShaderNotSet    = errors.New("shader is not set")
+	ErrLightsNotSet    = errors.New("lights are not set")
+	ErrIndexizerNotSet = errors.New("indexizer is not set")
+	ErrChunkBits       = errors.New("chunk bits are out of range")
+	ErrBufferSize      = errors.New("buffer size doesn't match dimensions")
+)
+
+// Valid range of ChunkBits and Rasterizer.TileBits.
+const (
+	MinChunkBits = 1
+	MaxChunkBits = 12
+)
+
+// DrawError is an error of drawing operation.
+type DrawError struct {
+	// Operation, e.g. "Rasterizer.DrawRectangle".
+	Op  string
+	Err error
+}
+
+func (e *DrawError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *DrawError) Unwrap() error {
+	return e.Err
+}
+
+// validateRaster checks input common for all rasterizer primitives.
+func validateRaster(op string, buf []byte, bw, bh int, shader bool, l Lights, ind Indexizer, chunkBits int) error {
+	var err error
+	switch {
+	case buf == nil:
+		err = ErrBufferNotSet
+	case !shader:
+		err = ErrShaderNotSet
+	case l == nil:
+		err = ErrLightsNotSet
+	case ind == nil:
+		err = ErrIndexizerNotSet
+	case chunkBits != 0 && (chunkBits < MinChunkBits || chunkBits > MaxChunkBits):
+		err = fmt.Errorf("%w: %v", ErrChunkBits, chunkBits)
+	case bw < 0 || bh < 0 || len(buf) < bw*bh:
+		err = fmt.Errorf("%w: %vx%v, buffer length %v", ErrBufferSize, bw, bh, len(buf))
+	}
+	if err != nil {
+		return &DrawError{Op: op, Err: err}
+	}
+	return nil
+}
+
+// Validate checks that image dimensions match pixels buffer size.
+func (iim IndexedImage) Validate() error {
+	if iim.Width < 0 || iim.Height < 0 || len(iim.Pixels) != iim.Width*iim.Height {
+		return fmt.Errorf("%w: %vx%v, pixels length %v", ErrBufferSize, iim.Width, iim.Height, len(iim.Pixels))
+	}
+	return nil
+}
+
+// reportError passes error of drawing function to ErrorHandler.
+// Without handler, each distinct error is logged once.
+func (d *Display) reportError(err error) {
+	if err == nil {
+		return
+	}
+	if d.ErrorHandler != nil {
+		d.ErrorHandler(err)
+		return
+	}
+	if d.reportedError == nil {
+		d.reportedError = make(map[string]struct{})
+	}
+	msg := err.Error()
+	if _, ok := d.reportedError[msg]; ok {
+		return
+	}
+	d.reportedError[msg] = struct{}{}
+	log.Printf("Error: %v", msg)
+}
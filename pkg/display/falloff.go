@@ -0,0 +1,103 @@
+package display
+
+import "math"
+
+// Falloff is a light attenuation curve. It returns light factor (0-1) for
+// squared distance from the light d2 and squared fall radius r2.
+// Squared values avoid square roots for curves that don't need them.
+// Any function can be used as a custom curve. Built-in curves handle zero
+// fall radius like FalloffDisc: only the light center is lit.
+type Falloff func(d2, r2 float64) float64
+
+// FalloffDefault is the default curve: at fall radius light is reduced
+// by two times. The dependency is quadric. Light never reaches zero,
+// use MaxRadius of the source to limit it.
+func FalloffDefault(d2, r2 float64) float64 {
+	if r2 <= 0 {
+		return FalloffDisc(d2, r2)
+	}
+	return r2 / (d2*10 + r2)
+}
+
+// FalloffLinear reduces light linearly to zero at fall radius.
+func FalloffLinear(d2, r2 float64) float64 {
+	if r2 <= 0 {
+		return FalloffDisc(d2, r2)
+	}
+	t := 1 - math.Sqrt(d2/r2)
+	if t < 0 {
+		return 0
+	}
+	return t
+}
+
+// FalloffSmoothstep reduces light to zero at fall radius along
+// smoothstep curve: it's flat near the center and at the edge.
+func FalloffSmoothstep(d2, r2 float64) float64 {
+	t := FalloffLinear(d2, r2)
+	return t * t * (3 - 2*t)
+}
+
+// FalloffDisc produces full light inside fall radius and none outside.
+func FalloffDisc(d2, r2 float64) float64 {
+	if d2 <= r2 {
+		return 1
+	}
+	return 0
+}
+
+// FalloffInverseSquare returns physically based inverse-square curve
+// (light is halved at fall radius) which is smoothly windowed to reach
+// zero at cutoff distance.
+func FalloffInverseSquare(cutoff float64) Falloff {
+	c2 := cutoff * cutoff
+	return func(d2, r2 float64) float64 {
+		if d2 >= c2 {
+			return 0
+		}
+		if r2 <= 0 {
+			return FalloffDisc(d2, r2)
+		}
+		w := 1 - (d2*d2)/(c2*c2)
+		return r2 / (d2 + r2) * w * w
+	}
+}
+
+// FalloffTable returns curve given by lookup table. Values are light
+// factors sampled uniformly from the center (first value) to fall radius
+// (last value) and interpolated linearly. Beyond fall radius the last value
+// is used. Empty table produces no light.
+func FalloffTable(lut []float64) Falloff {
+	lut = append([]float64(nil), lut...)
+	return func(d2, r2 float64) float64 {
+		n := len(lut)
+		if n == 0 {
+			return 0
+		}
+		if r2 <= 0 {
+			if d2 <= 0 {
+				return lut[0]
+			}
+			return lut[n-1]
+		}
+		p := math.Sqrt(d2/r2) * float64(n-1)
+		i := int(p)
+		if i >= n-1 {
+			return lut[n-1]
+		}
+		t := p - float64(i)
+		return lut[i]*(1-t) + lut[i+1]*t
+	}
+}
+
+// attenuate returns light factor for the curve (FalloffDefault when nil)
+// limited by max radius (0 for no limit).
+func attenuate(f Falloff, d2, r2, maxRadius float64) float64 {
+	if maxRadius > 0 && d2 >= maxRadius*maxRadius {
+		return 0
+	}
+	if f == nil {
+		return FalloffDefault(d2, r2)
+	}
+	return f(d2, r2)
+}
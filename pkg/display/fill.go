@@ -0,0 +1,253 @@
+package display
+
+import (
+	"context"
+	"math"
+)
+
+// Fill calculates color of the filled area in screen space, so that
+// adjacent shapes using the same fill join seamlessly.
+type Fill interface {
+	// FillColor returns color index at the given point.
+	// 0 means no color: pixel is left untouched.
+	FillColor(l Lights, ind Indexizer, posx, posy int) byte
+}
+
+// Pattern is 8x8 1-bit pattern. Each byte is a row, the highest bit
+// is the leftmost pixel.
+type Pattern [8]byte
+
+// Commonly used patterns.
+var (
+	PatternSolid    = Pattern{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	PatternChecker  = Pattern{0xaa, 0x55, 0xaa, 0x55, 0xaa, 0x55, 0xaa, 0x55}
+	PatternDots     = Pattern{0x88, 0x00, 0x22, 0x00, 0x88, 0x00, 0x22, 0x00}
+	PatternHLines   = Pattern{0xff, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00}
+	PatternVLines   = Pattern{0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa, 0xaa}
+	PatternDiagonal = Pattern{0x80, 0x40, 0x20, 0x10, 0x08, 0x04, 0x02, 0x01}
+	PatternCross    = Pattern{0x10, 0x10, 0x10, 0xff, 0x10, 0x10, 0x10, 0x10}
+	PatternBricks   = Pattern{0xff, 0x80, 0x80, 0x80, 0xff, 0x08, 0x08, 0x08}
+)
+
+// Bit returns pattern bit at the given point. Pattern is tiled infinitely.
+func (p *Pattern) Bit(x, y int) bool {
+	return p[y&7]&(0x80>>uint(x&7)) != 0
+}
+
+// PatternFill fills area with tiled 1-bit pattern using two fixed
+// color indices.
+type PatternFill struct {
+	Pattern Pattern
+
+	// Color index for set bits. 0 is transparent.
+	Fg byte
+	// Color index for unset bits. 0 is transparent.
+	Bg byte
+
+	// Pattern origin in screen coordinates.
+	OX, OY int
+}
+
+func (f *PatternFill) FillColor(l Lights, ind Indexizer, posx, posy int) byte {
+	if f.Pattern.Bit(posx-f.OX, posy-f.OY) {
+		return f.Fg
+	}
+	return f.Bg
+}
+
+// LinearGradient fills area with intensity changing linearly from
+// point (X0, Y0) to point (X1, Y1). Intensity is constant beyond the points.
+// Intensity is passed through Lights and Indexizer, so Indexizer dithering
+// is used to produce the transitions.
+type LinearGradient struct {
+	X0, Y0 float64
+	X1, Y1 float64
+
+	// Intensity (1-255) at the start and end points.
+	From, To byte
+}
+
+func (f *LinearGradient) FillColor(l Lights, ind Indexizer, posx, posy int) byte {
+	dx, dy := f.X1-f.X0, f.Y1-f.Y0
+	d2 := dx*dx + dy*dy
+	var t float64
+	if d2 > 0 {
+		t = ((float64(posx)+0.5-f.X0)*dx + (float64(posy)+0.5-f.Y0)*dy) / d2
+	}
+	return shade(l, ind, lerpIntensity(f.From, f.To, t), posx, posy)
+}
+
+// RadialGradient fills area with intensity changing linearly from
+// the center (X, Y) to the Radius. Intensity is constant beyond the radius.
+// Intensity is passed through Lights and Indexizer, so Indexizer dithering
+// is used to produce the transitions.
+type RadialGradient struct {
+	X, Y   float64
+	Radius float64
+
+	// Intensity (1-255) at the center and at the radius.
+	From, To byte
+}
+
+func (f *RadialGradient) FillColor(l Lights, ind Indexizer, posx, posy int) byte {
+	var t float64
+	if f.Radius > 0 {
+		dx := float64(posx) + 0.5 - f.X
+		dy := float64(posy) + 0.5 - f.Y
+		t = math.Sqrt(dx*dx+dy*dy) / f.Radius
+	}
+	return shade(l, ind, lerpIntensity(f.From, f.To, t), posx, posy)
+}
+
+// lerpIntensity interpolates intensity between a and b, t is clamped to 0-1.
+// Result is never 0 (no color).
+func lerpIntensity(a, b byte, t float64) byte {
+	if t < 0 {
+		t = 0
+	} else if t > 1 {
+		t = 1
+	}
+	v := math.Floor(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	if v < 1 {
+		v = 1
+	}
+	return byte(v)
+}
+
+// TextureFill fills area with a region of the image (e. g. atlas) repeated
+// in both directions. Texels are passed through Lights and Indexizer as
+// sprite pixels are. Texel 0 is transparent.
+// Shaders check the image and clip the region once, when they are created.
+type TextureFill struct {
+	Image *IndexedImage
+
+	// Region of the image to repeat. It's clipped to the image bounds.
+	X, Y          int
+	Width, Height int
+
+	// Texture origin in screen coordinates.
+	OX, OY int
+}
+
+func (f *TextureFill) FillColor(l Lights, ind Indexizer, posx, posy int) byte {
+	tf, ok := f.prepare()
+	if !ok {
+		return 0
+	}
+	return tf.FillColor(l, ind, posx, posy)
+}
+
+// prepare checks image and clips region. Fill is called from rasterizer
+// workers, where panic can't be recovered, so invalid image or region
+// produce no color.
+func (f *TextureFill) prepare() (textureFill, bool) {
+	if f.Image == nil || f.Image.Validate() != nil {
+		return textureFill{}, false
+	}
+	x, y, w, h := f.Image.clip(f.X, f.Y, f.Width, f.Height)
+	if w <= 0 || h <= 0 {
+		return textureFill{}, false
+	}
+	return textureFill{
+		iim: *f.Image,
+		x:   x,
+		y:   y,
+		w:   w,
+		h:   h,
+		ox:  f.OX,
+		oy:  f.OY,
+	}, true
+}
+
+// textureFill is TextureFill with checked image and clipped region.
+type textureFill struct {
+	iim        IndexedImage
+	x, y, w, h int
+	ox, oy     int
+}
+
+func (f textureFill) FillColor(l Lights, ind Indexizer, posx, posy int) byte {
+	tx := (posx - f.ox) % f.w
+	if tx < 0 {
+		tx += f.w
+	}
+	ty := (posy - f.oy) % f.h
+	if ty < 0 {
+		ty += f.h
+	}
+	c := f.iim.Pixels[f.x+tx+(f.y+ty)*f.iim.Width]
+	if c == 0 {
+		return 0
+	}
+	return shade(l, ind, c, posx, posy)
+}
+
+// noFill produces no color.
+type noFill struct{}
+
+func (noFill) FillColor(l Lights, ind Indexizer, posx, posy int) byte {
+	return 0
+}
+
+// prepareFill returns fill to use in shader. Checks that don't depend
+// on pixel position are done here once instead of for each pixel.
+func prepareFill(f Fill) Fill {
+	tf, ok := f.(*TextureFill)
+	if !ok {
+		return f
+	}
+	if p, ok := tf.prepare(); ok {
+		return p
+	}
+	return noFill{}
+}
+
+// RectShaderFill returns rectangle shader that fills rectangle using the fill.
+func RectShaderFill(f Fill) func(o *RectangleShaderOpts) {
+	f = prepareFill(f)
+	return func(o *RectangleShaderOpts) {
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		col := f.FillColor(o.Lights, o.Indexizer, x, y)
+		if col == 0 {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, col, x, y)
+	}
+}
+
+// TriShaderFill returns triangle shader that fills triangle using the fill.
+func TriShaderFill(f Fill) func(o *TriangleShaderOpts) {
+	f = prepareFill(f)
+	return func(o *TriangleShaderOpts) {
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		col := f.FillColor(o.Lights, o.Indexizer, x, y)
+		if col == 0 {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, col, x, y)
+	}
+}
+
+// FillRectangle fills rectangle on the screen using the fill.
+func (d *Display) FillRectangle(x, y, w, h float64, f Fill) {
+	d.reportError(d.Rasterizer.DrawRectangleContext(context.Background(), RectangleInfo{
+		RectangleRasterInput: RectangleRasterInput{
+			Buffer:       d.Screen.Pixels,
+			BufferWidth:  d.Screen.Width,
+			BufferHeight: d.Screen.Height,
+			Shader:       RectShaderFill(f),
+			Lights:       d.Lights,
+			Indexizer:    d.Indexizer,
+		},
+		X: x,
+		Y: y,
+		W: w,
+		H: h,
+	}))
+}
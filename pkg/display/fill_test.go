@@ -0,0 +1,57 @@
+package display
+
+import "testing"
+
+func TestTextureFill(t *testing.T) {
+	img := testImage(4, 3,
+		1, 2, 3, 4,
+		5, 0, 7, 8,
+		9, 10, 11, 12,
+	)
+	tests := []struct {
+		name string
+		fill TextureFill
+		// Texture pixels for screen pixels (0, 0) to (3, 0) and (0, 1) to (3, 1),
+		// 0 for no color.
+		want []byte
+	}{
+		{"region", TextureFill{Image: &img, X: 1, Y: 1, Width: 2, Height: 2},
+			[]byte{0, 7, 0, 7, 10, 11, 10, 11}},
+		{"origin", TextureFill{Image: &img, X: 1, Y: 1, Width: 2, Height: 2, OX: -1, OY: 1},
+			[]byte{11, 10, 11, 10, 7, 0, 7, 0}},
+		{"clipped", TextureFill{Image: &img, X: 2, Y: -1, Width: 5, Height: 2},
+			[]byte{3, 4, 3, 4, 3, 4, 3, 4}},
+		{"outside", TextureFill{Image: &img, X: 4, Y: 0, Width: 2, Height: 2},
+			[]byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"empty region", TextureFill{Image: &img, X: 1, Y: 1},
+			[]byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"no image", TextureFill{Width: 2, Height: 2},
+			[]byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"invalid image", TextureFill{Image: &IndexedImage{Width: 4, Height: 4, Pixels: img.Pixels}, Width: 2, Height: 2},
+			[]byte{0, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Display{Lights: FullLight, Indexizer: identityIndexizer{}}
+			d.Rasterizer.SingleThreaded = true
+			// Rasterizer doesn't draw the last row and column of buffer.
+			d.InitBuffers(5, 3)
+			d.FillRectangle(0, 0, 4, 2, &tt.fill)
+			checkImage(t, d.Screen.SubImage(0, 0, 4, 2), testImage(4, 2, tt.want...))
+
+			// Direct calls match shader.
+			for i, want := range tt.want {
+				if got := tt.fill.FillColor(FullLight, identityIndexizer{}, i%4, i/4); got != want {
+					t.Errorf("FillColor(%v, %v): got %v, want %v", i%4, i/4, got, want)
+				}
+			}
+		})
+	}
+}
+
+// identityIndexizer maps full light intensity back to texel value.
+type identityIndexizer struct{}
+
+func (identityIndexizer) Indexize(intens float64, posx, posy int) byte {
+	return byte(intens*255 + 0.5)
+}
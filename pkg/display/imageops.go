@@ -0,0 +1,152 @@
+package display
+
+import "bytes"
+
+// Clone returns a copy of the image with its own pixels buffer.
+func (iim IndexedImage) Clone() IndexedImage {
+	iim.Pixels = append([]byte(nil), iim.Pixels...)
+	return iim
+}
+
+// Equal checks whether images have the same size and pixels.
+// Number of colors is not compared.
+func (iim IndexedImage) Equal(other IndexedImage) bool {
+	return iim.Width == other.Width && iim.Height == other.Height &&
+		bytes.Equal(iim.Pixels, other.Pixels)
+}
+
+// clip clips rectangle to image bounds. Returned size is never negative.
+func (iim IndexedImage) clip(x, y, w, h int) (int, int, int, int) {
+	if x < 0 {
+		w += x
+		x = 0
+	}
+	if y < 0 {
+		h += y
+		y = 0
+	}
+	if x+w > iim.Width {
+		w = iim.Width - x
+	}
+	if y+h > iim.Height {
+		h = iim.Height - y
+	}
+	if w <= 0 || h <= 0 {
+		return x, y, 0, 0
+	}
+	return x, y, w, h
+}
+
+// SubImage returns a copy of the image rectangle with top-left corner
+// at (x, y). Rectangle is clipped to the image bounds.
+func (iim IndexedImage) SubImage(x, y, w, h int) IndexedImage {
+	x, y, w, h = iim.clip(x, y, w, h)
+	sub := IndexedImage{
+		Width:  w,
+		Height: h,
+		Pixels: make([]byte, w*h),
+		Colors: iim.Colors,
+	}
+	for row := 0; row < h; row++ {
+		so := x + (y+row)*iim.Width
+		copy(sub.Pixels[row*w:(row+1)*w], iim.Pixels[so:so+w])
+	}
+	return sub
+}
+
+// Crop cuts the image to the rectangle with top-left corner at (x, y)
+// in place. Rectangle is clipped to the image bounds.
+func (iim *IndexedImage) Crop(x, y, w, h int) {
+	x, y, w, h = iim.clip(x, y, w, h)
+	// Rows only move towards the buffer start, so copying is safe.
+	for row := 0; row < h; row++ {
+		so := x + (y+row)*iim.Width
+		copy(iim.Pixels[row*w:(row+1)*w], iim.Pixels[so:so+w])
+	}
+	iim.Width = w
+	iim.Height = h
+	iim.Pixels = iim.Pixels[:w*h]
+}
+
+// Blit copies src image into this one with top-left corner at (x, y).
+// Pixels with color 0 (no color) are skipped. Image parts outside
+// the bounds are clipped.
+func (iim IndexedImage) Blit(src IndexedImage, x, y int) {
+	dx, dy, w, h := iim.clip(x, y, src.Width, src.Height)
+	sx, sy := dx-x, dy-y
+	for row := 0; row < h; row++ {
+		s := src.Pixels[sx+(sy+row)*src.Width:][:w]
+		d := iim.Pixels[dx+(dy+row)*iim.Width:][:w]
+		for i, c := range s {
+			if c != 0 {
+				d[i] = c
+			}
+		}
+	}
+}
+
+// FlipH mirrors the image horizontally in place.
+func (iim IndexedImage) FlipH() {
+	for y := 0; y < iim.Height; y++ {
+		row := iim.Pixels[y*iim.Width : (y+1)*iim.Width]
+		for i, j := 0, len(row)-1; i < j; i, j = i+1, j-1 {
+			row[i], row[j] = row[j], row[i]
+		}
+	}
+}
+
+// FlipV mirrors the image vertically in place.
+func (iim IndexedImage) FlipV() {
+	w := iim.Width
+	for i, j := 0, iim.Height-1; i < j; i, j = i+1, j-1 {
+		a := iim.Pixels[i*w : (i+1)*w]
+		b := iim.Pixels[j*w : (j+1)*w]
+		for k := range a {
+			a[k], b[k] = b[k], a[k]
+		}
+	}
+}
+
+// Rotate90 returns a copy of the image rotated 90 degrees clockwise.
+func (iim IndexedImage) Rotate90() IndexedImage {
+	rot := IndexedImage{
+		Width:  iim.Height,
+		Height: iim.Width,
+		Pixels: make([]byte, len(iim.Pixels)),
+		Colors: iim.Colors,
+	}
+	for y := 0; y < iim.Height; y++ {
+		for x := 0; x < iim.Width; x++ {
+			rot.Pixels[(rot.Width-1-y)+x*rot.Width] = iim.Pixels[x+y*iim.Width]
+		}
+	}
+	return rot
+}
+
+// Scale returns a copy of the image resized to w by h pixels
+// (nearest neighbour). Negative size is treated as 0.
+func (iim IndexedImage) Scale(w, h int) IndexedImage {
+	if w < 0 {
+		w = 0
+	}
+	if h < 0 {
+		h = 0
+	}
+	sc := IndexedImage{
+		Width:  w,
+		Height: h,
+		Pixels: make([]byte, w*h),
+		Colors: iim.Colors,
+	}
+	if iim.Width == 0 || iim.Height == 0 {
+		return sc
+	}
+	for y := 0; y < h; y++ {
+		sy := y * iim.Height / h
+		row := iim.Pixels[sy*iim.Width : (sy+1)*iim.Width]
+		for x := 0; x < w; x++ {
+			sc.Pixels[x+y*w] = row[x*iim.Width/w]
+		}
+	}
+	return sc
+}
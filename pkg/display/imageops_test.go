@@ -0,0 +1,231 @@
+package display
+
+import "testing"
+
+// testImage returns image w by h with given pixels.
+func testImage(w, h int, pix ...byte) IndexedImage {
+	if len(pix) != w*h {
+		panic("test image size mismatch")
+	}
+	return IndexedImage{Width: w, Height: h, Pixels: pix}
+}
+
+// 3x2 image used as source in tests:
+//
+//	1 2 3
+//	4 5 6
+var testImg = testImage(3, 2,
+	1, 2, 3,
+	4, 5, 6,
+)
+
+var emptyImg = testImage(0, 0)
+
+func checkImage(t *testing.T, got, want IndexedImage) {
+	t.Helper()
+	if !got.Equal(want) {
+		t.Errorf("got %vx%v %v, want %vx%v %v",
+			got.Width, got.Height, got.Pixels, want.Width, want.Height, want.Pixels)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("invalid image: %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	for _, src := range []IndexedImage{testImg, emptyImg} {
+		c := src.Clone()
+		checkImage(t, c, src)
+		if len(c.Pixels) > 0 {
+			c.Pixels[0] = 9
+			if src.Pixels[0] == 9 {
+				t.Errorf("clone shares pixels with source")
+			}
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b IndexedImage
+		want bool
+	}{
+		{"same", testImg, testImg.Clone(), true},
+		{"empty", emptyImg, IndexedImage{}, true},
+		{"pixel", testImg, testImage(3, 2, 1, 2, 3, 4, 5, 7), false},
+		{"size", testImg, testImage(2, 3, 1, 2, 3, 4, 5, 6), false},
+		{"empty and not", emptyImg, testImg, false},
+		{"colors ignored", testImg, IndexedImage{Width: 3, Height: 2, Pixels: testImg.Pixels, Colors: 4}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Equal(tt.b); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// rectTests are shared by SubImage and Crop tests.
+var rectTests = []struct {
+	name       string
+	src        IndexedImage
+	x, y, w, h int
+	want       IndexedImage
+}{
+	{"whole", testImg, 0, 0, 3, 2, testImg},
+	{"inner", testImg, 1, 0, 2, 2, testImage(2, 2, 2, 3, 5, 6)},
+	{"bottom right", testImg, 1, 1, 2, 1, testImage(2, 1, 5, 6)},
+	{"larger", testImg, -1, -1, 10, 10, testImg},
+	{"negative offset", testImg, -1, -1, 3, 2, testImage(2, 1, 1, 2)},
+	{"past right", testImg, 2, 0, 5, 2, testImage(1, 2, 3, 6)},
+	{"outside right", testImg, 3, 0, 2, 2, emptyImg},
+	{"outside below", testImg, 0, 5, 2, 2, emptyImg},
+	{"outside left", testImg, -5, 0, 2, 2, emptyImg},
+	{"outside above", testImg, 0, -2, 3, 2, emptyImg},
+	{"zero size", testImg, 1, 1, 0, 0, emptyImg},
+	{"negative size", testImg, 1, 1, -2, 1, emptyImg},
+	{"empty source", emptyImg, 0, 0, 2, 2, emptyImg},
+}
+
+func TestSubImage(t *testing.T) {
+	for _, tt := range rectTests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tt.src.Clone()
+			checkImage(t, src.SubImage(tt.x, tt.y, tt.w, tt.h), tt.want)
+			checkImage(t, src, tt.src)
+		})
+	}
+}
+
+func TestCrop(t *testing.T) {
+	for _, tt := range rectTests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := tt.src.Clone()
+			img.Crop(tt.x, tt.y, tt.w, tt.h)
+			checkImage(t, img, tt.want)
+		})
+	}
+}
+
+func TestCropOverlapping(t *testing.T) {
+	// Source and destination rows overlap in the buffer.
+	img := testImage(4, 4,
+		1, 2, 3, 4,
+		5, 6, 7, 8,
+		9, 10, 11, 12,
+		13, 14, 15, 16,
+	)
+	img.Crop(1, 1, 3, 3)
+	checkImage(t, img, testImage(3, 3,
+		6, 7, 8,
+		10, 11, 12,
+		14, 15, 16,
+	))
+}
+
+func TestBlit(t *testing.T) {
+	src := testImage(2, 2,
+		7, 0,
+		8, 9,
+	)
+	tests := []struct {
+		name string
+		src  IndexedImage
+		x, y int
+		want IndexedImage
+	}{
+		{"top left", src, 0, 0, testImage(3, 2, 7, 2, 3, 8, 9, 6)},
+		{"bottom right", src, 2, 1, testImage(3, 2, 1, 2, 3, 4, 5, 7)},
+		{"negative offset", src, -1, -1, testImage(3, 2, 9, 2, 3, 4, 5, 6)},
+		{"outside right", src, 3, 0, testImg},
+		{"outside above", src, 0, -2, testImg},
+		{"far outside", src, -10, 10, testImg},
+		{"empty source", emptyImg, 1, 1, testImg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := testImg.Clone()
+			dst.Blit(tt.src, tt.x, tt.y)
+			checkImage(t, dst, tt.want)
+		})
+	}
+
+	t.Run("empty destination", func(t *testing.T) {
+		dst := emptyImg.Clone()
+		dst.Blit(src, 0, 0)
+		checkImage(t, dst, emptyImg)
+	})
+}
+
+func TestFlip(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   IndexedImage
+		wantH IndexedImage
+		wantV IndexedImage
+	}{
+		{"3x2", testImg, testImage(3, 2, 3, 2, 1, 6, 5, 4), testImage(3, 2, 4, 5, 6, 1, 2, 3)},
+		{"3x3", testImage(3, 3, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+			testImage(3, 3, 3, 2, 1, 6, 5, 4, 9, 8, 7),
+			testImage(3, 3, 7, 8, 9, 4, 5, 6, 1, 2, 3)},
+		{"1x1", testImage(1, 1, 5), testImage(1, 1, 5), testImage(1, 1, 5)},
+		{"empty", emptyImg, emptyImg, emptyImg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.src.Clone()
+			h.FlipH()
+			checkImage(t, h, tt.wantH)
+			v := tt.src.Clone()
+			v.FlipV()
+			checkImage(t, v, tt.wantV)
+		})
+	}
+}
+
+func TestRotate90(t *testing.T) {
+	tests := []struct {
+		name string
+		src  IndexedImage
+		want IndexedImage
+	}{
+		{"3x2", testImg, testImage(2, 3, 4, 1, 5, 2, 6, 3)},
+		{"1x3", testImage(1, 3, 1, 2, 3), testImage(3, 1, 3, 2, 1)},
+		{"empty", emptyImg, emptyImg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkImage(t, tt.src.Rotate90(), tt.want)
+		})
+	}
+
+	img := testImg
+	for i := 0; i < 4; i++ {
+		img = img.Rotate90()
+	}
+	checkImage(t, img, testImg)
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name string
+		src  IndexedImage
+		w, h int
+		want IndexedImage
+	}{
+		{"same", testImg, 3, 2, testImg},
+		{"double", testImage(2, 1, 1, 2), 4, 2, testImage(4, 2, 1, 1, 2, 2, 1, 1, 2, 2)},
+		{"half", testImage(4, 2, 1, 2, 3, 4, 5, 6, 7, 8), 2, 1, testImage(2, 1, 1, 3)},
+		{"zero", testImg, 0, 0, emptyImg},
+		{"zero width", testImg, 0, 2, testImage(0, 2)},
+		{"negative", testImg, -3, -2, emptyImg},
+		{"empty source", emptyImg, 2, 2, testImage(2, 2, 0, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkImage(t, tt.src.Scale(tt.w, tt.h), tt.want)
+		})
+	}
+}
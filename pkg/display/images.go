@@ -3,6 +3,8 @@ package display
 import (
 	"fmt"
 	"image"
+	"image/color"
+	"math"
 )
 
 // IndexedImage is a structure that represents image in indexed mode.
@@ -26,28 +28,109 @@ type FromImageOpts struct {
 	// Alpha threshold. Alpha that is lower this is considered invisible.
 	// Default is 0.5. Less than 0 produces no invisible pixels.
 	AlphaThreshold float64
+
+	// Weights of red, green and blue components in pixel intensity.
+	// Default is DefaultLuminanceWeights.
+	Weights [3]float64
+
+	// Gamma correction: intensity is raised to the power of 1/Gamma.
+	// Values above 1 brighten midtones. Default is 1 (no correction).
+	Gamma float64
 }
 
+// DefaultLuminanceWeights are weights of red, green and blue components
+// used to calculate pixel intensity by default.
+var DefaultLuminanceWeights = [3]float64{0.21, 0.72, 0.07}
+
 type ToRGBAOpts struct {
 	// Prepared Pixels buffer to use.
 	Pixels []byte
 
 	// Palette to convert indexed color to RGBA.
 	Palette Palette
+
+	// Precomputed lookup table to convert indexed color to RGBA.
+	// When set, Palette is ignored.
+	LUT *ColorLUT
+
+	// Integer upscale factor (nearest neighbour). Default is 1.
+	Scale int
+}
+
+// size returns RGBA buffer size for the image.
+func (o *ToRGBAOpts) size(iim IndexedImage) int {
+	return 4 * iim.Width * iim.Height * o.Scale * o.Scale
+}
+
+// prepare validates image and options and allocates buffer if needed.
+func (o *ToRGBAOpts) prepare(iim IndexedImage) error {
+	if err := iim.Validate(); err != nil {
+		return err
+	}
+	if o.Scale == 0 {
+		o.Scale = 1
+	}
+	if o.Scale < 0 {
+		return fmt.Errorf("%w: scale %v", ErrBufferSize, o.Scale)
+	}
+	if o.Pixels == nil {
+		o.Pixels = make([]byte, o.size(iim))
+	} else if len(o.Pixels) != o.size(iim) {
+		return fmt.Errorf("%w: passed: %v expected: %v", ErrBufferSize, len(o.Pixels), o.size(iim))
+	}
+	return nil
+}
+
+// rgbaRows converts rows y0 to y1 (exclusive) of the image to RGBA
+// upscaling them by scale.
+func rgbaRows(iim IndexedImage, dst []byte, lut *ColorLUT, scale, y0, y1 int) {
+	// Length of destination row, bytes.
+	dw := 4 * iim.Width * scale
+	for y := y0; y < y1; y++ {
+		src := iim.Pixels[y*iim.Width : (y+1)*iim.Width]
+		row := dst[y*scale*dw : (y*scale+1)*dw]
+		if scale == 1 {
+			for i, c := range src {
+				*(*[4]byte)(row[i*4 : i*4+4]) = lut[c]
+			}
+			continue
+		}
+		o := 0
+		for _, c := range src {
+			col := lut[c]
+			for k := 0; k < scale; k++ {
+				*(*[4]byte)(row[o : o+4]) = col
+				o += 4
+			}
+		}
+		for k := 1; k < scale; k++ {
+			copy(dst[(y*scale+k)*dw:(y*scale+k+1)*dw], row)
+		}
+	}
 }
 
 // IndexedImageFromImage converts stdlib image.Image to IndexedImage with a given number
 // of colors. Image is first converted to grascale, then intensity of each pixel
 // is mapped to the color range. 0 is reserved for no color. No color is set for
 // pixels which alpha is lower than alpha threshold.
+// Images with non-zero bounds origin (e.g. from SubImage) are supported.
+// *image.NRGBA, *image.RGBA, *image.Gray and *image.Paletted are converted
+// without per-pixel interface calls.
 func IndexedImageFromImage(img image.Image, o FromImageOpts) IndexedImage {
-	w, h := img.Bounds().Max.X, img.Bounds().Max.Y
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
 	if o.Colors < 1 || o.Colors > 255 {
 		o.Colors = 255
 	}
 	if o.AlphaThreshold == 0 {
 		o.AlphaThreshold = 0.5
 	}
+	if o.Weights == ([3]float64{}) {
+		o.Weights = DefaultLuminanceWeights
+	}
+	if o.Gamma == 0 {
+		o.Gamma = 1
+	}
 	iim := IndexedImage{
 		Width:  w,
 		Height: h,
@@ -58,25 +141,81 @@ func IndexedImageFromImage(img image.Image, o FromImageOpts) IndexedImage {
 		return iim
 	}
 
-	for i := 0; i < len(iim.Pixels); i++ {
-		r, g, b, a := img.At(i%iim.Width, i/iim.Width).RGBA()
-		fa := float64(a)
-		if fa/0xffff < o.AlphaThreshold {
-			continue
+	switch im := img.(type) {
+	case *image.NRGBA:
+		for y := 0; y < h; y++ {
+			row := im.Pix[im.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				p := row[x*4 : x*4+4]
+				iim.Pixels[x+y*w] = o.index(color.NRGBA{p[0], p[1], p[2], p[3]}.RGBA())
+			}
+		}
+	case *image.RGBA:
+		for y := 0; y < h; y++ {
+			row := im.Pix[im.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				p := row[x*4 : x*4+4]
+				iim.Pixels[x+y*w] = o.index(color.RGBA{p[0], p[1], p[2], p[3]}.RGBA())
+			}
 		}
-		// Compensate for alpha-premultiplication and scale to 0-1.
-		intensity := float64(r)/fa*0.21 + float64(g)/fa*0.72 + float64(b)/fa*0.07
-		const cap = float64(0xffff) / 0x10000
-		if intensity > cap {
-			intensity = cap
-		} else if intensity < 0 {
-			intensity = 0
+	case *image.Gray:
+		var lut [256]byte
+		for i := range lut {
+			lut[i] = o.index(color.Gray{byte(i)}.RGBA())
+		}
+		for y := 0; y < h; y++ {
+			row := im.Pix[im.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				iim.Pixels[x+y*w] = lut[row[x]]
+			}
+		}
+	case *image.Paletted:
+		// Indices beyond palette produce no color.
+		var lut [256]byte
+		for i, c := range im.Palette {
+			if i < len(lut) {
+				lut[i] = o.index(c.RGBA())
+			}
+		}
+		for y := 0; y < h; y++ {
+			row := im.Pix[im.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				iim.Pixels[x+y*w] = lut[row[x]]
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				iim.Pixels[x+y*w] = o.index(img.At(b.Min.X+x, b.Min.Y+y).RGBA())
+			}
 		}
-		iim.Pixels[i] = byte(intensity * (float64(o.Colors + 1)))
 	}
 	return iim
 }
 
+// index converts alpha-premultiplied color to color index.
+func (o *FromImageOpts) index(r, g, b, a uint32) byte {
+	fa := float64(a)
+	if fa/0xffff < o.AlphaThreshold {
+		return 0
+	}
+	if a == 0 {
+		return 0
+	}
+	// Compensate for alpha-premultiplication and scale to 0-1.
+	intensity := float64(r)/fa*o.Weights[0] + float64(g)/fa*o.Weights[1] + float64(b)/fa*o.Weights[2]
+	if o.Gamma != 1 && intensity > 0 {
+		intensity = math.Pow(intensity, 1/o.Gamma)
+	}
+	const cap = float64(0xffff) / 0x10000
+	if intensity > cap {
+		intensity = cap
+	} else if intensity < 0 {
+		intensity = 0
+	}
+	return byte(intensity * (float64(o.Colors + 1)))
+}
+
 // Fill buffer with provided byte.
 func (iim IndexedImage) Fill(v byte) {
 	// Preload the first value into the slice
@@ -89,22 +228,32 @@ func (iim IndexedImage) Fill(v byte) {
 }
 
 // ToRGBA converts IndexedImage to RGBA mode.
-// Resulting slice is 4 times larger than Pixels size.
+// Resulting slice is 4 * Scale * Scale times larger than Pixels size.
 // You can provide existing buffer in ToRGBAOpts.Pixels
 // to avoid memory allocation, but it need to be exactly
-// 4 times larger than Pixels of this image or function
-// will panic.
+// of that size or function will panic.
+// Use TryToRGBA to get an error instead.
 func (iim IndexedImage) ToRGBA(o ToRGBAOpts) []byte {
-	if o.Pixels == nil {
-		o.Pixels = make([]byte, 4*iim.Width*4*iim.Height)
-	} else {
-		if len(o.Pixels) != 4*iim.Width*iim.Height {
-			panic(fmt.Sprintf("IndexedImage.ToRGBA: Passed bytes size do not match, passed: %v expected: %v",
-				len(o.Pixels), 4*iim.Width*iim.Height))
-		}
+	pix, err := iim.TryToRGBA(o)
+	if err != nil {
+		panic(err)
+	}
+	return pix
+}
+
+// TryToRGBA is like ToRGBA, but returns *DrawError instead of panicking
+// when buffer size doesn't match.
+// Conversion runs on the calling goroutine, see Rasterizer.ToRGBA
+// for parallel version.
+func (iim IndexedImage) TryToRGBA(o ToRGBAOpts) ([]byte, error) {
+	if err := o.prepare(iim); err != nil {
+		return nil, &DrawError{Op: "IndexedImage.ToRGBA", Err: err}
 	}
-	for i := range iim.Pixels {
-		o.Palette.Colorize(o.Pixels[i*4:i*4+3], iim.Pixels[i])
+	lut := o.LUT
+	if lut == nil {
+		lut = new(ColorLUT)
+		lut.Set(o.Palette)
 	}
-	return o.Pixels
+	rgbaRows(iim, o.Pixels, lut, o.Scale, 0, iim.Height)
+	return o.Pixels, nil
 }
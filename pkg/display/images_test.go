@@ -0,0 +1,141 @@
+package display
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// atImage hides concrete image type, so the generic At path is used.
+type atImage struct {
+	image.Image
+}
+
+// fillTestImage sets each pixel of the image to a distinct color,
+// including transparent ones.
+func fillTestImage(img interface {
+	image.Image
+	Set(x, y int, c color.Color)
+}) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.Set(x, y, color.NRGBA{
+				R: byte(x * 37),
+				G: byte(y * 59),
+				B: byte(x*y + 11),
+				A: byte((x + y) * 40),
+			})
+		}
+	}
+}
+
+func TestIndexedImageFromImageFastPaths(t *testing.T) {
+	r := image.Rect(0, 0, 13, 9)
+	pal := color.Palette{color.Transparent}
+	for i := 0; i < 40; i++ {
+		pal = append(pal, color.NRGBA{byte(i * 6), byte(255 - i*5), byte(i * 3), byte(100 + i*3)})
+	}
+	nrgba := image.NewNRGBA(r)
+	rgba := image.NewRGBA(r)
+	gray := image.NewGray(r)
+	paletted := image.NewPaletted(r, pal)
+	for _, img := range []interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}{nrgba, rgba, gray, paletted} {
+		fillTestImage(img)
+	}
+
+	sub := image.Rect(3, 2, 11, 8)
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"NRGBA", nrgba},
+		{"RGBA", rgba},
+		{"Gray", gray},
+		{"Paletted", paletted},
+		{"NRGBA sub", nrgba.SubImage(sub)},
+		{"RGBA sub", rgba.SubImage(sub)},
+		{"Gray sub", gray.SubImage(sub)},
+		{"Paletted sub", paletted.SubImage(sub)},
+		{"empty", nrgba.SubImage(image.Rect(4, 4, 4, 4))},
+	}
+	opts := []FromImageOpts{
+		{},
+		{Colors: 4, AlphaThreshold: -1},
+		{Colors: 16, Weights: [3]float64{0.5, 0.2, 0.3}, Gamma: 2.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, o := range opts {
+				got := IndexedImageFromImage(tt.img, o)
+				want := IndexedImageFromImage(atImage{tt.img}, o)
+				checkImage(t, got, want)
+				if got.Colors != want.Colors {
+					t.Errorf("got %v colors, want %v", got.Colors, want.Colors)
+				}
+			}
+		})
+	}
+
+	// Index beyond palette produces no color (At panics on it).
+	bad := image.NewPaletted(r, pal)
+	fillTestImage(bad)
+	bad.Pix[5] = 200
+	if c := IndexedImageFromImage(bad, FromImageOpts{AlphaThreshold: -1}).Pixels[5]; c != 0 {
+		t.Errorf("got color %v for index beyond palette, want 0", c)
+	}
+
+	// Sub-image matches the part of full image.
+	full := IndexedImageFromImage(nrgba, FromImageOpts{})
+	checkImage(t, IndexedImageFromImage(nrgba.SubImage(sub), FromImageOpts{}),
+		full.SubImage(sub.Min.X, sub.Min.Y, sub.Dx(), sub.Dy()))
+}
+
+func TestIndexedImageFromImageWeights(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 255, 0, 255})
+	img.Set(2, 0, color.NRGBA{0, 0, 255, 255})
+
+	tests := []struct {
+		name    string
+		weights [3]float64
+		want    []byte
+	}{
+		{"default", [3]float64{}, []byte{1, 3, 0}},
+		{"red", [3]float64{1, 0, 0}, []byte{4, 0, 0}},
+		{"blue", [3]float64{0, 0, 1}, []byte{0, 0, 4}},
+		{"equal", [3]float64{1.0 / 3, 1.0 / 3, 1.0 / 3}, []byte{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IndexedImageFromImage(img, FromImageOpts{Colors: 4, Weights: tt.weights})
+			checkImage(t, got, testImage(3, 1, tt.want...))
+		})
+	}
+}
+
+func TestIndexedImageFromImageGamma(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 1))
+	img.Pix = []byte{64, 128, 255}
+
+	tests := []struct {
+		name  string
+		gamma float64
+		want  []byte
+	}{
+		{"default", 0, []byte{64, 128, 255}},
+		{"none", 1, []byte{64, 128, 255}},
+		{"brighten", 2, []byte{128, 181, 255}},
+		{"darken", 0.5, []byte{16, 64, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IndexedImageFromImage(img, FromImageOpts{Gamma: tt.gamma})
+			checkImage(t, got, testImage(3, 1, tt.want...))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package display
+
+import "math"
+
+// LightMap is a light source that holds precomputed (baked) contribution
+// of static light sources. Add it to LightSet.Sources along with dynamic
+// sources.
+// Static sources are placed in world coordinates. Screen point (x, y)
+// corresponds to world point (x + OX, y + OY), so the map scrolls with
+// the camera.
+// Contribution is baked at the corners of square cells and interpolated
+// bilinearly; with CellBits 0 it's baked for each pixel.
+// Don't bake while drawing is in progress.
+type LightMap struct {
+	// World rectangle covered by the map. Outside it map adds no light.
+	X, Y          int
+	Width, Height int
+
+	// Cell size as a power of 2.
+	CellBits int
+
+	// Camera position: world coordinates of the screen top-left corner.
+	OX, OY int
+
+	// Static light sources in world coordinates.
+	Sources []LightSource
+
+	Removed bool
+
+	// Baked offset and scale at cell corners.
+	cols, rows int
+	offs       []float32
+	scale      []float32
+}
+
+// Bake evaluates all static sources for the whole map. Call it after
+// changing map rectangle or cell size.
+func (m *LightMap) Bake() {
+	cell := 1 << m.CellBits
+	m.cols = (m.Width+cell-1)>>m.CellBits + 1
+	m.rows = (m.Height+cell-1)>>m.CellBits + 1
+	if m.Width <= 0 || m.Height <= 0 {
+		m.cols, m.rows = 0, 0
+	}
+	m.offs = make([]float32, m.cols*m.rows)
+	m.scale = make([]float32, m.cols*m.rows)
+	m.bake(0, 0, m.cols-1, m.rows-1)
+}
+
+// Rebake evaluates static sources for the world rectangle only. Call it
+// with the old and the new bounds of static source that was changed.
+func (m *LightMap) Rebake(x0, y0, x1, y1 float64) {
+	if m.offs == nil {
+		m.Bake()
+		return
+	}
+	cell := float64(int(1) << m.CellBits)
+	// Samples that are interpolated inside the rectangle.
+	i0 := int(math.Floor((x0 - float64(m.X)) / cell))
+	j0 := int(math.Floor((y0 - float64(m.Y)) / cell))
+	i1 := int(math.Ceil((x1 - float64(m.X)) / cell))
+	j1 := int(math.Ceil((y1 - float64(m.Y)) / cell))
+	m.bake(maxInt(i0, 0), maxInt(j0, 0), minInt(i1, m.cols-1), minInt(j1, m.rows-1))
+}
+
+// AddSource adds static source and bakes its contribution.
+func (m *LightMap) AddSource(s LightSource) {
+	m.Sources = append(m.Sources, s)
+	m.rebakeSource(s)
+}
+
+// RemoveSource removes static source and rebakes the map without it.
+func (m *LightMap) RemoveSource(s LightSource) {
+	for i, ms := range m.Sources {
+		if ms == s {
+			m.Sources = append(m.Sources[:i], m.Sources[i+1:]...)
+			m.rebakeSource(s)
+			return
+		}
+	}
+}
+
+// rebakeSource rebakes bounds of the source, or the whole map
+// if source is not bounded.
+func (m *LightMap) rebakeSource(s LightSource) {
+	if b, ok := s.(BoundedSource); ok {
+		if x0, y0, x1, y1, ok := b.Bounds(); ok {
+			m.Rebake(x0, y0, x1, y1)
+			return
+		}
+	}
+	m.Bake()
+}
+
+// bake evaluates static sources for samples in the range (inclusive).
+func (m *LightMap) bake(i0, j0, i1, j1 int) {
+	for j := j0; j <= j1; j++ {
+		wy := m.Y + j<<m.CellBits
+		for i := i0; i <= i1; i++ {
+			wx := m.X + i<<m.CellBits
+			var offs, scale float64
+			for _, s := range m.Sources {
+				if !s.Alive() {
+					continue
+				}
+				of, sc := s.OffsetScale(wx, wy)
+				offs += of
+				scale += sc
+			}
+			m.offs[i+j*m.cols] = float32(offs)
+			m.scale[i+j*m.cols] = float32(scale)
+		}
+	}
+}
+
+func (m *LightMap) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	wx, wy := posx+m.OX-m.X, posy+m.OY-m.Y
+	if wx < 0 || wy < 0 || wx >= m.Width || wy >= m.Height || m.offs == nil {
+		return 0, 0
+	}
+	if m.CellBits == 0 {
+		i := wx + wy*m.cols
+		return float64(m.offs[i]), float64(m.scale[i])
+	}
+	cell := float64(int(1) << m.CellBits)
+	ix, iy := wx>>m.CellBits, wy>>m.CellBits
+	fx := float64(wx&(1<<m.CellBits-1)) / cell
+	fy := float64(wy&(1<<m.CellBits-1)) / cell
+	i := ix + iy*m.cols
+	lerp := func(v []float32) float64 {
+		top := float64(v[i])*(1-fx) + float64(v[i+1])*fx
+		bottom := float64(v[i+m.cols])*(1-fx) + float64(v[i+m.cols+1])*fx
+		return top*(1-fy) + bottom*fy
+	}
+	return lerp(m.offs), lerp(m.scale)
+}
+
+func (m *LightMap) Alive() bool {
+	return !m.Removed
+}
+
+// Bounds returns map rectangle in screen coordinates.
+func (m *LightMap) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	x0, y0 = float64(m.X-m.OX), float64(m.Y-m.OY)
+	return x0, y0, x0 + float64(m.Width), y0 + float64(m.Height), true
+}
@@ -14,8 +14,8 @@ type Lights interface {
 // LightSource provides information about lighting for each point of screen.
 // The most common implementation is a point light source that has a diminishing
 // light the farther it is located from the light source.
-// When light source Alive method returns false, the light source will be dropped
-// from the current model.
+// When light source Alive method returns false, the light source is ignored
+// and removed from LightSet on the next Update (or Prune).
 type LightSource interface {
 	OffsetScale(posx, posy int) (offs float64, scale float64)
 	Alive() bool
@@ -26,30 +26,66 @@ type LightSource interface {
 // and offset at each pixel position.
 // Offset is added to original intensity (scaled to 0-1), then it's
 // multiplied by scale.
-// The more sources are used, the slower drawing functions are.
+// The more sources are used, the slower drawing functions are. Sources with
+// limited radius can be culled for each rasterizer chunk, see UpdateGrid.
+// Sources that are not alive are ignored and removed by Update.
 type LightSet struct {
 	Sources   []LightSource
 	MinScale  float64
 	MaxScale  float64
 	MinOffset float64
 	MaxOffset float64
+
+	// Size of light culling grid cell as a power of 2, see UpdateGrid.
+	// Default is 5 (32 pixels).
+	GridBits int
+
+	grid *lightGrid
+	// Changes of sources made by set methods, see UpdateGrid.
+	gen int
+	// Time in seconds, see Update.
+	time float64
 }
 
 // Light calculates light intensity (0-1) at the point using current light model.
+// Light doesn't modify the set, so it's safe to call it from rasterizer workers.
 func (l *LightSet) Light(val byte, posx, posy int) (intens float64) {
+	return l.light(l.Sources, val, posx, posy)
+}
+
+// light calculates light intensity using given sources.
+func (l *LightSet) light(sources []LightSource, val byte, posx, posy int) (intens float64) {
+	intens, _ = l.eval(sources, val, nil, false, posx, posy)
+	return intens
+}
+
+// eval calculates light intensity using given sources and surface
+// normal (nil for no normal). With wantRamp, it also returns ramp of the
+// source that provides more than half of light scale (0 if none).
+func (l *LightSet) eval(sources []LightSource, val byte, n *Normal, wantRamp bool, posx, posy int) (intens float64, ramp int) {
 	offs := l.MinOffset
 	scale := l.MinScale
 
-	for i := 0; i < len(l.Sources); i++ {
-		if !l.Sources[i].Alive() {
-			n := len(l.Sources) - 1
-			l.Sources[i] = l.Sources[n]
-			l.Sources[n] = nil
-			l.Sources = l.Sources[:n]
+	var dominant LightSource
+	var dominantScale float64
+	for _, s := range sources {
+		if !s.Alive() {
+			continue
+		}
+		of, sc := s.OffsetScale(posx, posy)
+		if n != nil && (of != 0 || sc != 0) {
+			d := dotNormal(s, n, posx, posy)
+			of *= d
+			sc *= d
 		}
-		of, sc := l.Sources[i].OffsetScale(posx, posy)
 		offs += of
 		scale += sc
+		if wantRamp && sc > dominantScale {
+			dominant, dominantScale = s, sc
+		}
+	}
+	if dominant != nil && dominantScale*2 > scale {
+		ramp = sourceRamp(dominant)
 	}
 	if offs > l.MaxOffset {
 		offs = l.MaxOffset
@@ -62,7 +98,33 @@ func (l *LightSet) Light(val byte, posx, posy int) (intens float64) {
 		scale = l.MinScale
 	}
 
-	return (float64(val)/255 + offs) * scale
+	return (float64(val)/255 + offs) * scale, ramp
+}
+
+// Prune removes sources that are not alive. Order of the remaining
+// sources is preserved. Don't call it while drawing is in progress.
+func (l *LightSet) Prune() {
+	n := 0
+	for _, s := range l.Sources {
+		if s.Alive() {
+			l.Sources[n] = s
+			n++
+		}
+	}
+	if n == len(l.Sources) {
+		return
+	}
+	for i := n; i < len(l.Sources); i++ {
+		l.Sources[i] = nil
+	}
+	l.Sources = l.Sources[:n]
+	l.gen++
+}
+
+// add adds the source to the set.
+func (l *LightSet) add(s LightSource) {
+	l.Sources = append(l.Sources, s)
+	l.gen++
 }
 
 // TrackCircle adds a circle light source tied to an object that
@@ -73,23 +135,33 @@ func (l *LightSet) TrackCircle(t Tracked, intens, rfall float64) {
 		Intensity:  intens,
 		FallRadius: rfall,
 	}
-	l.Sources = append(l.Sources, cs)
+	l.add(cs)
 }
 
 // CircleSource is a point light source with circle area, which tracks an object
 // position, size and alive status.
-// At fall radius intensity is reduced by two times. The dependency is quadric.
+// By default at fall radius intensity is reduced by two times. The dependency
+// is quadric.
 type CircleSource struct {
 	Tracked    Tracked
 	Intensity  float64
 	FallRadius float64
+
+	// Attenuation curve. Default is FalloffDefault.
+	Falloff Falloff
+	// Light is exactly zero beyond this distance. 0 is unlimited.
+	MaxRadius float64
+
+	// Height of light above the screen for normal mapping.
+	// Default is FallRadius.
+	Height float64
 }
 
 // Tracked is an interface that describes object that can be tracked by
 // the light system. Required methods are Pos which gives current position
 // of a light source, SizeMod that can control momentary radius of the light,
 // and Alive property, which, when returns false, will cause the removing
-// of the tracked object.
+// of the light source on the next LightSet.Update.
 // When creating game, any objects that acts as a point light source, needs
 // to implement this interface. When object is deleted (removed from the game),
 // set an internal variable to return Alive equals to false, so light source
@@ -106,16 +178,11 @@ func (s *CircleSource) OffsetScale(posx, posy int) (offs float64, scale float64)
 	dy := float64(posy) - y
 	d2 := dx*dx + dy*dy
 	fr2 := s.FallRadius * s.FallRadius * s.Tracked.SizeMod()
-	scale += s.Intensity * fr2 / (d2*10 + fr2)
-	return 0, scale
+	return 0, s.Intensity * attenuate(s.Falloff, d2, fr2, s.MaxRadius)
 }
 
 func (s *CircleSource) Alive() bool {
-	al := s.Tracked.Alive()
-	if !al {
-		s.Tracked = nil
-	}
-	return al
+	return s.Tracked.Alive()
 }
 
 // FixedLight returns a constant intensity of light regardless of screen position.
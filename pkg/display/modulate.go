@@ -0,0 +1,198 @@
+package display
+
+import "math"
+
+// AnimatedSource is an optional interface of LightSource that changes
+// over time. LightSet.Update calls SetTime for such sources.
+type AnimatedSource interface {
+	LightSource
+	// SetTime sets time in seconds since the start of LightSet.
+	SetTime(t float64)
+}
+
+// Update advances time of the set by dt seconds, updates animated
+// sources and removes sources that are not alive (see Prune).
+// Call it once per frame, not while drawing is in progress.
+func (l *LightSet) Update(dt float64) {
+	l.time += dt
+	for _, s := range l.Sources {
+		if as, ok := s.(AnimatedSource); ok {
+			as.SetTime(l.time)
+		}
+	}
+	l.Prune()
+}
+
+// Time returns time of the set in seconds.
+func (l *LightSet) Time() float64 {
+	return l.time
+}
+
+// Modulator changes light over time.
+type Modulator interface {
+	// Modulate returns light factor at time t, seconds since modulation
+	// started. When alive is false, light is removed from the set
+	// on LightSet.Update.
+	Modulate(t float64) (factor float64, alive bool)
+}
+
+// Modulated is a light source which contribution is multiplied by
+// modulators, e.g. a flickering torch. Modulators are evaluated once
+// per frame, on LightSet.Update.
+type Modulated struct {
+	Source     LightSource
+	Modulators []Modulator
+
+	start  float64
+	begun  bool
+	factor float64
+	alive  bool
+}
+
+// Modulate wraps the light source with modulators and adds it to the set.
+func (l *LightSet) Modulate(s LightSource, mods ...Modulator) *Modulated {
+	m := &Modulated{
+		Source:     s,
+		Modulators: mods,
+	}
+	m.SetTime(l.time)
+	l.add(m)
+	return m
+}
+
+func (m *Modulated) SetTime(t float64) {
+	if !m.begun {
+		m.start = t
+		m.begun = true
+	}
+	m.factor, m.alive = 1, true
+	for _, mod := range m.Modulators {
+		f, alive := mod.Modulate(t - m.start)
+		m.factor *= f
+		m.alive = m.alive && alive
+	}
+	if as, ok := m.Source.(AnimatedSource); ok {
+		as.SetTime(t)
+	}
+}
+
+func (m *Modulated) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	if !m.begun {
+		return m.Source.OffsetScale(posx, posy)
+	}
+	if m.factor == 0 {
+		return 0, 0
+	}
+	offs, scale = m.Source.OffsetScale(posx, posy)
+	return offs * m.factor, scale * m.factor
+}
+
+func (m *Modulated) Alive() bool {
+	return (!m.begun || m.alive) && m.Source.Alive()
+}
+
+func (m *Modulated) Unwrap() LightSource {
+	return m.Source
+}
+
+func (m *Modulated) Bounds() (x0, y0, x1, y1 float64, ok bool) {
+	if b, ok := m.Source.(BoundedSource); ok {
+		return b.Bounds()
+	}
+	return 0, 0, 0, 0, false
+}
+
+// Flicker randomly changes light like a flame.
+type Flicker struct {
+	// Maximum light reduction, 0-1.
+	Amount float64
+	// Number of changes per second.
+	Speed float64
+	// Different seeds make lights flicker independently.
+	Seed int
+}
+
+func (f Flicker) Modulate(t float64) (float64, bool) {
+	return 1 - f.Amount*valueNoise(t*f.Speed, f.Seed), true
+}
+
+// valueNoise returns smooth noise (0-1) at x.
+func valueNoise(x float64, seed int) float64 {
+	i := math.Floor(x)
+	t := x - i
+	t = t * t * (3 - 2*t)
+	a := hashNoise(int64(i), seed)
+	b := hashNoise(int64(i)+1, seed)
+	return a*(1-t) + b*t
+}
+
+// hashNoise returns pseudo-random value (0-1) for the lattice point.
+func hashNoise(i int64, seed int) float64 {
+	h := uint64(i)*0x9e3779b97f4a7c15 ^ uint64(seed)*0xbf58476d1ce4e5b9
+	h ^= h >> 31
+	h *= 0x94d049bb133111eb
+	h ^= h >> 29
+	return float64(h>>11) / (1 << 53)
+}
+
+// Pulse smoothly changes light between Min and Max factors along sine.
+type Pulse struct {
+	Min, Max float64
+	// Period in seconds.
+	Period float64
+	// Phase, fraction of period (0-1).
+	Phase float64
+}
+
+func (p Pulse) Modulate(t float64) (float64, bool) {
+	if p.Period <= 0 {
+		return p.Max, true
+	}
+	s := math.Sin(2 * math.Pi * (t/p.Period + p.Phase))
+	return p.Min + (p.Max-p.Min)*(s+1)/2, true
+}
+
+// Strobe turns light on and off.
+type Strobe struct {
+	// Period in seconds.
+	Period float64
+	// Fraction of period (0-1) light is on. Default is 0.5.
+	Duty float64
+}
+
+func (s Strobe) Modulate(t float64) (float64, bool) {
+	if s.Period <= 0 {
+		return 1, true
+	}
+	duty := s.Duty
+	if duty == 0 {
+		duty = 0.5
+	}
+	ph := t/s.Period - math.Floor(t/s.Period)
+	if ph < duty {
+		return 1, true
+	}
+	return 0, true
+}
+
+// Lifetime fades light in, keeps it for Duration and fades it out,
+// then light is removed from the set on LightSet.Update. Use it for
+// explosions and muzzle flashes.
+type Lifetime struct {
+	// Durations in seconds.
+	FadeIn   float64
+	Duration float64
+	FadeOut  float64
+}
+
+func (l Lifetime) Modulate(t float64) (float64, bool) {
+	switch {
+	case t < l.FadeIn:
+		return t / l.FadeIn, true
+	case t < l.FadeIn+l.Duration:
+		return 1, true
+	case t < l.FadeIn+l.Duration+l.FadeOut:
+		return 1 - (t-l.FadeIn-l.Duration)/l.FadeOut, true
+	}
+	return 0, false
+}
@@ -0,0 +1,25 @@
+package display
+
+import "testing"
+
+func TestLifetimeRemovesLight(t *testing.T) {
+	l := &LightSet{MaxScale: 2, MaxOffset: 1}
+	l.TrackCircle(testTracked{0, 0}, 1, 10)
+	m := l.Modulate(&CircleSource{Tracked: testTracked{5, 5}, Intensity: 1, FallRadius: 10},
+		Lifetime{FadeIn: 0.1, Duration: 0.5, FadeOut: 0.2})
+
+	for i, want := range []int{2, 2, 2, 1, 1} {
+		l.Update(0.25)
+		if got := len(l.Sources); got != want {
+			t.Fatalf("update %v: got %v sources, want %v", i+1, got, want)
+		}
+	}
+	if m.Alive() {
+		t.Errorf("expired light is alive")
+	}
+	for _, s := range l.Sources {
+		if s == LightSource(m) {
+			t.Errorf("expired light is not removed")
+		}
+	}
+}
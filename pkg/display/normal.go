@@ -0,0 +1,177 @@
+package display
+
+import (
+	"image"
+	"math"
+)
+
+// Normal is a unit vector perpendicular to the sprite surface.
+// X points right, Y points down (screen axes), Z points towards the viewer.
+type Normal struct {
+	X, Y, Z float32
+}
+
+// FlatNormal is a normal of surface parallel to the screen.
+var FlatNormal = Normal{0, 0, 1}
+
+// NormalMap is a normal for each pixel of an atlas.
+type NormalMap struct {
+	Width   int
+	Height  int
+	Normals []Normal
+}
+
+// NormalMapFromImage converts normal map image to NormalMap.
+// Red, green and blue encode X, Y and Z in the range -1 to 1. Green points
+// up (OpenGL convention). Transparent pixels get FlatNormal.
+func NormalMapFromImage(img image.Image) NormalMap {
+	b := img.Bounds()
+	nm := NormalMap{
+		Width:   b.Dx(),
+		Height:  b.Dy(),
+		Normals: make([]Normal, b.Dx()*b.Dy()),
+	}
+	for y := 0; y < nm.Height; y++ {
+		for x := 0; x < nm.Width; x++ {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			n := FlatNormal
+			if a > 0 {
+				fa := float64(a)
+				nx := float64(r)/fa*2 - 1
+				ny := -(float64(g)/fa*2 - 1)
+				nz := float64(bl)/fa*2 - 1
+				if l := math.Sqrt(nx*nx + ny*ny + nz*nz); l > 0 {
+					n = Normal{float32(nx / l), float32(ny / l), float32(nz / l)}
+				}
+			}
+			nm.Normals[x+y*nm.Width] = n
+		}
+	}
+	return nm
+}
+
+// NormalLights is an optional interface of Lights that can shade
+// a surface with the normal.
+type NormalLights interface {
+	Lights
+	LightNormal(val byte, n Normal, posx, posy int) (intens float64)
+}
+
+// NormalSource is an optional interface of LightSource that has direction
+// to the light. With normal mapping, source contribution is multiplied
+// by N·L. Sources without direction light all surfaces equally.
+type NormalSource interface {
+	LightSource
+	// LightDir returns unit vector from the point to the light.
+	LightDir(posx, posy int) (x, y, z float64)
+}
+
+// LightNormal is like Light, but shades surface with normal n.
+func (l *LightSet) LightNormal(val byte, n Normal, posx, posy int) (intens float64) {
+	intens, _ = l.eval(l.Sources, val, &n, false, posx, posy)
+	return intens
+}
+
+func (c *cellLights) LightNormal(val byte, n Normal, posx, posy int) float64 {
+	intens, _ := c.set.eval(c.sources, val, &n, false, posx, posy)
+	return intens
+}
+
+func (l *countingLights) LightNormal(val byte, n Normal, posx, posy int) float64 {
+	l.evals++
+	return lightNormal(l.Lights, val, n, posx, posy)
+}
+
+// lightNormal calculates light intensity with normal if lights support it.
+func lightNormal(l Lights, val byte, n Normal, posx, posy int) float64 {
+	if nl, ok := l.(NormalLights); ok {
+		return nl.LightNormal(val, n, posx, posy)
+	}
+	return l.Light(val, posx, posy)
+}
+
+// shadeNormal is like shade, but with surface normal.
+func shadeNormal(l Lights, ind Indexizer, val byte, n Normal, x, y int) byte {
+	in := lightNormal(l, val, n, x, y)
+	return ind.Indexize(in, x, y)
+}
+
+// dotNormal returns N·L clamped to 0 for the source, or 1 if source
+// has no direction.
+func dotNormal(s LightSource, n *Normal, posx, posy int) float64 {
+	s = unwrapSource(s, func(s LightSource) bool {
+		_, ok := s.(NormalSource)
+		return ok
+	})
+	ns, ok := s.(NormalSource)
+	if !ok {
+		return 1
+	}
+	x, y, z := ns.LightDir(posx, posy)
+	d := float64(n.X)*x + float64(n.Y)*y + float64(n.Z)*z
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// pointLightDir returns unit vector from the point to the light at (lx, ly)
+// and height h above the screen.
+func pointLightDir(lx, ly, h float64, posx, posy int) (x, y, z float64) {
+	x, y, z = lx-float64(posx), ly-float64(posy), h
+	l := math.Sqrt(x*x + y*y + z*z)
+	if l == 0 {
+		return 0, 0, 1
+	}
+	return x / l, y / l, z / l
+}
+
+func (s *CircleSource) LightDir(posx, posy int) (x, y, z float64) {
+	lx, ly := s.Tracked.Pos()
+	h := s.Height
+	if h == 0 {
+		h = s.FallRadius
+	}
+	return pointLightDir(lx, ly, h, posx, posy)
+}
+
+func (s *SpotSource) LightDir(posx, posy int) (x, y, z float64) {
+	lx, ly := s.Tracked.Pos()
+	h := s.Height
+	if h == 0 {
+		h = s.FallRadius
+	}
+	return pointLightDir(lx, ly, h, posx, posy)
+}
+
+func (s *DirectionalSource) LightDir(posx, posy int) (x, y, z float64) {
+	el := s.Elevation
+	if el == 0 {
+		el = math.Pi / 4
+	}
+	sin, cos := math.Sincos(s.Angle)
+	sinEl, cosEl := math.Sincos(el)
+	return cos * cosEl, sin * cosEl, sinEl
+}
+
+// RectShaderNormal is like RectShaderIndexed, but shades pixels with
+// normals from the normal map of the same size as the image.
+func RectShaderNormal(iim IndexedImage, nm *NormalMap, tx0, ty0, tx1, ty1 int) func(o *RectangleShaderOpts) {
+	tw := float64(tx1-tx0) + 1
+	th := float64(ty1-ty0) + 1
+
+	return func(o *RectangleShaderOpts) {
+		cx := int(float64(tw*o.Px)) + tx0
+		cy := int(float64(th*o.Py)) + ty0
+		c := iim.Pixels[cx+cy*iim.Width]
+		if c == 0 {
+			return
+		}
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		n := nm.Normals[cx+cy*nm.Width]
+		o.Blend.Put(o.Buffer, o.BufferOffset, shadeNormal(o.Lights, o.Indexizer, c, n, x, y), x, y)
+	}
+}
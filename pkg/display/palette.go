@@ -8,8 +8,10 @@ func (p Palette) ColorsNumber() int {
 	return len(p) / 3
 }
 
-// Colorize sets pixels to RGBA values from palette.
+// Colorize sets pixels to RGBA values from palette (canvas needs
+// to be at least 4 bytes long).
 // Index starts from 1, 0 sets no-color (zero alpha).
+// Use LUT to convert many pixels.
 func (p Palette) Colorize(canvas []byte, index byte) {
 	arr := [4]byte{}
 	if index > 0 && int(index) <= len(p)/3 {
@@ -19,3 +21,27 @@ func (p Palette) Colorize(canvas []byte, index byte) {
 	}
 	copy(canvas, arr[:])
 }
+
+// ColorLUT is a precomputed RGBA color for each of 256 color indices.
+type ColorLUT [256][4]byte
+
+// LUT returns precomputed RGBA lookup table for the palette.
+func (p Palette) LUT() *ColorLUT {
+	var l ColorLUT
+	l.Set(p)
+	return &l
+}
+
+// Set fills lookup table from the palette. Index 0 and indices beyond
+// palette size produce no-color (zero alpha).
+func (l *ColorLUT) Set(p Palette) {
+	*l = ColorLUT{}
+	n := p.ColorsNumber()
+	if n > 255 {
+		n = 255
+	}
+	for i := 1; i <= n; i++ {
+		j := (i - 1) * 3
+		l[i] = [4]byte{p[j], p[j+1], p[j+2], 255}
+	}
+}
@@ -0,0 +1,180 @@
+package display
+
+import (
+	"image"
+	"math"
+)
+
+// QuantizeDither is a dithering method used by QuantizeImage.
+type QuantizeDither int
+
+const (
+	// Nearest palette color without dithering.
+	QuantizeNoDither QuantizeDither = iota
+	// Floyd-Steinberg error diffusion.
+	QuantizeFloydSteinberg
+	// Ordered (Bayer 8x8) dithering.
+	QuantizeOrdered
+)
+
+// QuantizeOpts is a structure with options for QuantizeImage function.
+type QuantizeOpts struct {
+	// Target palette. Must have 1-255 colors.
+	Palette Palette
+
+	Dither QuantizeDither
+
+	// Amount of ordered dithering noise, fraction of color range.
+	// Default is 0.125.
+	Spread float64
+
+	// Alpha threshold. Alpha that is lower this is considered invisible.
+	// Default is 0.5. Less than 0 produces no invisible pixels.
+	AlphaThreshold float64
+}
+
+// QuantizeImage converts stdlib image.Image to IndexedImage with colors of
+// the palette. Each pixel gets the nearest palette color in perceptual
+// (Oklab) color space. Unlike IndexedImageFromImage, pixels are palette
+// indices rather than intensities, so the image should be drawn unlit
+// (see Sprite.Unlit). No color is set for pixels which alpha is lower than
+// alpha threshold.
+func QuantizeImage(img image.Image, o QuantizeOpts) IndexedImage {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	n := o.Palette.ColorsNumber()
+	if n > 255 {
+		n = 255
+	}
+	if o.AlphaThreshold == 0 {
+		o.AlphaThreshold = 0.5
+	}
+	if o.Spread == 0 {
+		o.Spread = 0.125
+	}
+	iim := IndexedImage{
+		Width:  w,
+		Height: h,
+		Pixels: make([]byte, w*h),
+		Colors: n,
+	}
+	if w == 0 || h == 0 || n == 0 {
+		return iim
+	}
+
+	pal := make([]oklab, n)
+	for i := range pal {
+		pal[i] = toOklab(float64(o.Palette[i*3]), float64(o.Palette[i*3+1]), float64(o.Palette[i*3+2]))
+	}
+
+	// Floyd-Steinberg errors of the current and the next rows
+	// with one pixel padding on each side.
+	var cur, next [][3]float64
+	if o.Dither == QuantizeFloydSteinberg {
+		cur = make([][3]float64, w+2)
+		next = make([][3]float64, w+2)
+	}
+	cache := make(map[[3]byte]byte)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			fa := float64(a)
+			if fa/0xffff < o.AlphaThreshold || a == 0 {
+				continue
+			}
+			// Compensate for alpha-premultiplication and scale to 0-255.
+			c := [3]float64{
+				float64(r) / fa * 255,
+				float64(g) / fa * 255,
+				float64(bl) / fa * 255,
+			}
+			switch o.Dither {
+			case QuantizeFloydSteinberg:
+				for k := range c {
+					c[k] += cur[x+1][k]
+				}
+			case QuantizeOrdered:
+				d := (bayerThreshold(x, y) - 0.5) * o.Spread * 255
+				for k := range c {
+					c[k] += d
+				}
+			}
+			var key [3]byte
+			for k := range c {
+				key[k] = clampByte(c[k])
+			}
+
+			col, ok := cache[key]
+			if !ok {
+				col = nearestColor(pal, toOklab(float64(key[0]), float64(key[1]), float64(key[2])))
+				cache[key] = col
+			}
+			iim.Pixels[x+y*w] = col
+
+			if o.Dither == QuantizeFloydSteinberg {
+				j := int(col-1) * 3
+				for k := range c {
+					e := c[k] - float64(o.Palette[j+k])
+					cur[x+2][k] += e * 7 / 16
+					next[x][k] += e * 3 / 16
+					next[x+1][k] += e * 5 / 16
+					next[x+2][k] += e * 1 / 16
+				}
+			}
+		}
+		if o.Dither == QuantizeFloydSteinberg {
+			cur, next = next, cur
+			for i := range next {
+				next[i] = [3]float64{}
+			}
+		}
+	}
+	return iim
+}
+
+// oklab is a color in Oklab perceptual color space.
+type oklab [3]float64
+
+// toOklab converts sRGB color (0-255 components) to Oklab.
+func toOklab(r, g, b float64) oklab {
+	lin := func(c float64) float64 {
+		c /= 255
+		if c <= 0.04045 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	r, g, b = lin(r), lin(g), lin(b)
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+	return oklab{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+// nearestColor returns index (starting from 1) of the palette color
+// closest to c.
+func nearestColor(pal []oklab, c oklab) byte {
+	best, bestDist := 0, math.Inf(1)
+	for i, p := range pal {
+		d0, d1, d2 := p[0]-c[0], p[1]-c[1], p[2]-c[2]
+		if d := d0*d0 + d1*d1 + d2*d2; d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return byte(best + 1)
+}
+
+func clampByte(v float64) byte {
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	}
+	return byte(v + 0.5)
+}
@@ -1,45 +1,310 @@
 package display
 
 import (
+	"context"
+	"fmt"
 	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Rasterizer struct {
+	// Number of worker goroutines. 0 (or negative) means the number
+	// of CPU cores.
 	Workers int
 
-	workChan chan chunk
+	// Process chunks on the calling goroutine instead of workers.
+	// Output of parallel rendering is identical to single-threaded one
+	// for the same input, so the mode is mostly useful when worker
+	// goroutines are unwanted (e.g. lockstep simulation, tools, tests).
+	SingleThreaded bool
+
+	// Tile size for batched drawing (in bits, e.g. 5 bits = 32 pixels).
+	// Default is 5.
+	TileBits int
+
+	// Collect performance statistics, see Stats.
+	// Slows down drawing a bit.
+	CollectStats bool
+
+	workChan chan work
 	started  bool
+	workers  sync.WaitGroup
+
+	// Batching state, see Begin.
+	batching bool
+	tiles    map[tileKey]*tileJob
+	tileList []*tileJob
+
+	stats *rasterStats
 }
 
 type chunk interface {
 	Process()
 }
 
+// work is a unit of work passed to rasterizer worker.
+// Chunk is skipped when context is done.
+type work struct {
+	ctx   context.Context
+	c     chunk
+	wg    *sync.WaitGroup
+	stats *rasterStats
+}
+
+// tileKey is a position of the tile in tiles grid.
+type tileKey struct {
+	x, y int
+}
+
+// tileJob is a list of chunks that belong to a single tile.
+// Chunks are processed in submission order until context is done.
+type tileJob struct {
+	ctx    context.Context
+	chunks []chunk
+}
+
+func (t *tileJob) Process() {
+	for _, c := range t.chunks {
+		if t.ctx.Err() != nil {
+			return
+		}
+		c.Process()
+	}
+}
+
+// Run starts rasterizer workers. It's called by drawing functions,
+// so there's no need to call it explicitly.
+// Workers are not started in single-threaded mode.
 func (r *Rasterizer) Run() {
-	if r.started {
+	if r.started || r.SingleThreaded {
 		return
 	}
-	if r.Workers == 0 {
+	if r.Workers <= 0 {
 		r.Workers = runtime.NumCPU()
 	}
-	r.workChan = make(chan chunk, r.Workers)
+	r.workChan = make(chan work, r.Workers)
 
+	r.workers.Add(r.Workers)
 	for i := 0; i < r.Workers; i++ {
 		go r.runWorker(i)
 	}
 	r.started = true
 }
 
+// Close stops rasterizer workers and waits for them to exit.
+// Rasterizer is restarted by the next drawing call (or Run).
+// Close must not be called concurrently with drawing.
+func (r *Rasterizer) Close() {
+	if !r.started {
+		return
+	}
+	close(r.workChan)
+	r.workers.Wait()
+	r.workChan = nil
+	r.started = false
+}
+
+// SetWorkers changes the number of workers. 0 (or negative) means the number
+// of CPU cores.
+// Running workers are stopped and the new ones are started.
+// SetWorkers must not be called concurrently with drawing.
+func (r *Rasterizer) SetWorkers(n int) {
+	started := r.started
+	r.Close()
+	r.Workers = n
+	if started {
+		r.Run()
+	}
+}
+
 func (r *Rasterizer) runWorker(num int) {
+	defer r.workers.Done()
 	for {
-		c, ok := <-r.workChan
+		w, ok := <-r.workChan
 		if !ok {
 			return
 		}
+		if w.ctx.Err() == nil {
+			if w.stats != nil && num < len(w.stats.busy) {
+				start := time.Now()
+				w.c.Process()
+				w.stats.addSince(&w.stats.busy[num], start)
+			} else {
+				w.c.Process()
+			}
+		}
+		w.wg.Done()
+	}
+}
+
+func (r *Rasterizer) renderChunk(ctx context.Context, c chunk, wg *sync.WaitGroup, st *rasterStats) {
+	if r.SingleThreaded {
+		r.processInline(ctx, c, st)
+		return
+	}
+	wg.Add(1)
+	r.workChan <- work{ctx: ctx, c: c, wg: wg, stats: st}
+}
+
+// processInline processes chunk on the calling goroutine.
+func (r *Rasterizer) processInline(ctx context.Context, c chunk, st *rasterStats) {
+	if ctx.Err() != nil {
+		return
+	}
+	if st == nil {
 		c.Process()
+		return
+	}
+	start := time.Now()
+	c.Process()
+	st.addSince(&st.busy[0], start)
+}
+
+// workerCount returns number of goroutines processing chunks.
+func (r *Rasterizer) workerCount() int {
+	if r.SingleThreaded {
+		return 1
+	}
+	r.Run()
+	return r.Workers
+}
+
+// Begin starts batch. Primitives drawn by DrawRectangle and DrawTriangle
+// (and functions based on them) are queued instead of being drawn
+// immediately, and are drawn by Flush. Queued primitives are binned into
+// screen tiles and each tile is processed by a single worker in submission
+// order, so overdraw is preserved.
+// Buffers must not be modified by other means until Flush; Display.DrawLine
+// and Display.DrawPolyline flush the batch before drawing.
+func (r *Rasterizer) Begin() {
+	if r.batching {
+		return
+	}
+	if r.tiles == nil {
+		r.tiles = make(map[tileKey]*tileJob)
+	}
+	r.batching = true
+}
+
+// Flush draws all primitives queued since Begin and ends the batch.
+// Does nothing if batch is not started.
+func (r *Rasterizer) Flush() {
+	r.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush, but aborts drawing when context is done.
+// Tiles that are already processed stay drawn, the rest are discarded.
+// Returns context error if drawing was aborted.
+func (r *Rasterizer) FlushContext(ctx context.Context) error {
+	if !r.batching {
+		return nil
+	}
+	r.batching = false
+	r.Run()
+	tm := newDrawTimer(r.frameStats())
+
+	var wg sync.WaitGroup
+	for _, t := range r.tileList {
+		if ctx.Err() != nil {
+			break
+		}
+		t.ctx = ctx
+		r.dispatch(ctx, t, &wg, &tm)
+	}
+	r.wait(&wg, &tm)
+
+	// Keep tiles allocated for the next batch.
+	for _, t := range r.tileList {
+		for i := range t.chunks {
+			t.chunks[i] = nil
+		}
+		t.chunks = t.chunks[:0]
+		t.ctx = nil
 	}
+	r.tileList = r.tileList[:0]
+	return ctx.Err()
 }
 
-func (r *Rasterizer) renderChunk(c chunk) {
-	r.workChan <- c
+// submit renders chunk with origin at (x, y) or puts it to tile queue
+// when batching.
+func (r *Rasterizer) submit(ctx context.Context, c chunk, x, y int, wg *sync.WaitGroup, tm *drawTimer) {
+	if tm.st != nil {
+		atomic.AddInt64(&tm.st.chunks, 1)
+	}
+	if !r.batching {
+		r.dispatch(ctx, c, wg, tm)
+		return
+	}
+	bits := r.tileBits()
+	k := tileKey{x >> bits, y >> bits}
+	t, ok := r.tiles[k]
+	if !ok {
+		t = &tileJob{}
+		r.tiles[k] = t
+	}
+	if len(t.chunks) == 0 {
+		r.tileList = append(r.tileList, t)
+	}
+	t.chunks = append(t.chunks, c)
+}
+
+// drawTimer measures phases of a drawing call for statistics.
+type drawTimer struct {
+	st *rasterStats
+	// Time drawing call started.
+	start time.Time
+	// Time spent passing chunks to workers or processing them inline.
+	dispatched time.Duration
+}
+
+// newDrawTimer starts timer of a drawing call. Timer does nothing when
+// statistics are not collected (st is nil).
+func newDrawTimer(st *rasterStats) drawTimer {
+	if st == nil {
+		return drawTimer{}
+	}
+	return drawTimer{st: st, start: time.Now()}
+}
+
+// dispatch renders chunk and counts the time it takes as waiting
+// for workers, not as setup.
+func (r *Rasterizer) dispatch(ctx context.Context, c chunk, wg *sync.WaitGroup, tm *drawTimer) {
+	if tm.st == nil {
+		r.renderChunk(ctx, c, wg, nil)
+		return
+	}
+	start := time.Now()
+	r.renderChunk(ctx, c, wg, tm.st)
+	tm.dispatched += time.Since(start)
+}
+
+// wait waits for workers to finish and updates statistics.
+func (r *Rasterizer) wait(wg *sync.WaitGroup, tm *drawTimer) {
+	st := tm.st
+	if st == nil {
+		wg.Wait()
+		return
+	}
+	atomic.AddInt64(&st.setup, int64(time.Since(tm.start)-tm.dispatched))
+	ws := time.Now()
+	wg.Wait()
+	atomic.AddInt64(&st.wait, int64(time.Since(ws)+tm.dispatched))
+}
+
+func (r *Rasterizer) tileBits() int {
+	if r.TileBits == 0 {
+		return 5
+	}
+	return r.TileBits
+}
+
+// validTileBits returns tile bits or error if they're out of range.
+func (r *Rasterizer) validTileBits(op string) (int, error) {
+	bits := r.tileBits()
+	if bits < MinChunkBits || bits > MaxChunkBits {
+		return 0, &DrawError{Op: op, Err: fmt.Errorf("%w: tile bits %v", ErrChunkBits, bits)}
+	}
+	return bits, nil
 }
@@ -0,0 +1,161 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+type testTracked struct {
+	x, y float64
+}
+
+func (t testTracked) Pos() (float64, float64) { return t.x, t.y }
+func (t testTracked) Alive() bool             { return true }
+func (t testTracked) SizeMod() float64        { return 1 }
+
+// newTestDisplay returns display for test scene. 0 workers means
+// single-threaded rasterizer.
+func newTestDisplay(workers int) *Display {
+	atlas := IndexedImage{
+		Width:  32,
+		Height: 32,
+		Pixels: make([]byte, 32*32),
+	}
+	for y := 0; y < atlas.Height; y++ {
+		for x := 0; x < atlas.Width; x++ {
+			// Leave some pixels empty.
+			atlas.Pixels[x+y*atlas.Width] = byte((x*7 + y*3) % 5 * 50)
+		}
+	}
+	lights := &LightSet{
+		MinScale:  0.2,
+		MaxScale:  1.5,
+		MaxOffset: 1,
+	}
+	lights.TrackCircle(testTracked{60, 40}, 1, 30)
+	lights.TrackCircle(testTracked{130, 90}, 0.5, 15)
+
+	d := &Display{
+		Atlases: map[string]*IndexedImage{"a": &atlas},
+		Sprites: map[string]*Sprite{
+			"s": {Atlas: &atlas, X: 4, Y: 4, Width: 16, Height: 16, XOrigin: 8, YOrigin: 8},
+		},
+		Lights:    lights,
+		Indexizer: Bits2,
+	}
+	if workers == 0 {
+		d.Rasterizer.SingleThreaded = true
+	} else {
+		d.Rasterizer.Workers = workers
+	}
+	d.InitBuffers(173, 131)
+	return d
+}
+
+// drawTestScene draws sprites and triangles with different blend modes.
+// With batch, the scene is drawn in a single batch.
+func drawTestScene(d *Display, batch bool) {
+	if batch {
+		d.Rasterizer.Begin()
+	}
+	blends := []Blend{
+		{},
+		{Mode: BlendAdd},
+		{Mode: BlendDarken, MaxIndex: 4},
+		{Transparency: 0.4},
+		{Mode: BlendAdd, Transparency: 0.6, Dither: DitherBlueNoise},
+	}
+	for i := 0; i < 40; i++ {
+		d.DrawSpriteAdvanced(DrawSpriteOpts{
+			Name:  "s",
+			DX:    float64(i*37%180) - 5.5,
+			DY:    float64(i*23%140) - 3.25,
+			DW:    float64(8 + i%5*9),
+			DH:    float64(8 + i%3*13),
+			Blend: blends[i%len(blends)],
+		})
+	}
+
+	atlas := *d.Atlases["a"]
+	for i := 0; i < 10; i++ {
+		x, y := float64(i*41%170), float64(i*29%130)
+		d.Rasterizer.DrawTriangle(TriangleInfo{
+			TriangleRasterInput: TriangleRasterInput{
+				Buffer:       d.Screen.Pixels,
+				BufferWidth:  d.Screen.Width,
+				BufferHeight: d.Screen.Height,
+				Shader:       TriShaderIndexed(atlas, 0, 0, 15, 0, 0, 15, 0, 1),
+				Lights:       d.Lights,
+				Indexizer:    d.Indexizer,
+				Blend:        blends[i%len(blends)],
+			},
+			X0: x, Y0: y,
+			X1: x + 50.5, Y1: y + 10,
+			X2: x + 5, Y2: y + 45.75,
+		})
+	}
+
+	for i := 0; i < 60; i++ {
+		d.DrawSpriteAdvanced(DrawSpriteOpts{
+			Name:  "s",
+			DX:    float64(i * 13 % 175),
+			DY:    float64(i * 31 % 135),
+			Blend: blends[i%len(blends)],
+		})
+	}
+	if batch {
+		d.Rasterizer.Flush()
+	}
+}
+
+func TestRasterizerDeterministic(t *testing.T) {
+	// Reference is drawn immediately by single thread.
+	ref := newTestDisplay(0)
+	drawTestScene(ref, false)
+	if bytes.Count(ref.Screen.Pixels, []byte{0}) == len(ref.Screen.Pixels) {
+		t.Fatal("reference screen is empty")
+	}
+
+	for _, workers := range []int{0, 1, 2, 3, 7, 16} {
+		for _, batch := range []bool{false, true} {
+			if workers == 0 && !batch {
+				continue
+			}
+			t.Run(fmt.Sprintf("workers=%v,batch=%v", workers, batch), func(t *testing.T) {
+				d := newTestDisplay(workers)
+				defer d.Close()
+				drawTestScene(d, batch)
+				if !bytes.Equal(d.Screen.Pixels, ref.Screen.Pixels) {
+					t.Errorf("screen differs from immediate single-threaded rendering")
+				}
+			})
+		}
+	}
+}
+
+func TestRasterizerNegativeWorkers(t *testing.T) {
+	ref := newTestDisplay(0)
+	drawTestScene(ref, false)
+
+	d := newTestDisplay(-1)
+	defer d.Close()
+	drawTestScene(d, true)
+	if d.Rasterizer.Workers <= 0 {
+		t.Errorf("got %v workers", d.Rasterizer.Workers)
+	}
+	d.Rasterizer.SetWorkers(-3)
+	d.Screen.Fill(0)
+	drawTestScene(d, true)
+	if !bytes.Equal(d.Screen.Pixels, ref.Screen.Pixels) {
+		t.Errorf("screen differs from single-threaded rendering")
+	}
+}
+
+func BenchmarkDrawSprite(b *testing.B) {
+	d := newTestDisplay(0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.DrawSpriteAdvanced(DrawSpriteOpts{Name: "s", DX: 50, DY: 50, DW: 64, DH: 64})
+	}
+}
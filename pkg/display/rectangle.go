@@ -1,8 +1,10 @@
 package display
 
 import (
+	"context"
 	"math"
 	"sync"
+	"sync/atomic"
 )
 
 // RectangleRasterInput part of options that is passed to rasterizer workers.
@@ -27,6 +29,9 @@ type RectangleRasterInput struct {
 	// Object to convert to index color.
 	Indexizer Indexizer
 
+	// Transparency and blend mode. Zero value draws opaque pixels.
+	Blend Blend
+
 	// Any other data to pass to shader.
 	Extra interface{}
 }
@@ -37,6 +42,12 @@ type RectangleRasterStatic struct {
 
 	// Percentage texture coords step per pixel.
 	Pxs, Pys float64
+
+	// Top-left pixel of the clipped rectangle and its percentage texture
+	// coords. Coords of each pixel are calculated from them, so they don't
+	// depend on chunk origin (e.g. when batching).
+	ox, oy   float64
+	pxo, pyo float64
 }
 
 // ShaderOpts options for shader.
@@ -80,31 +91,42 @@ type RectangleChunk struct {
 	// Chunk height in pixels (can be less than chunk size).
 	Height float64
 
-	WG *sync.WaitGroup
+	stats *rasterStats
 }
 
+// DrawRectangle draws rectangle using its shader. Call blocks until the rectangle
+// is drawn (or queued, see Begin).
+// Panics if input is invalid, use DrawRectangleContext to get an error instead.
 func (r *Rasterizer) DrawRectangle(ri RectangleInfo) {
-	r.Run()
-
-	if ri.Buffer == nil {
-		panic("Rasterizer.DrawRectangle: buffer is not set")
-	}
-	if ri.Shader == nil {
-		panic("Rasterizer.DrawRectangle: shader is not set")
+	if err := r.DrawRectangleContext(context.Background(), ri); err != nil {
+		panic(err)
 	}
-	if ri.Lights == nil {
-		panic("Rasterizer.DrawRectangle: lights are not set")
-	}
-	if ri.Indexizer == nil {
-		panic("Rasterizer.DrawRectangle: indexizer is not set")
+}
+
+// DrawRectangleContext is like DrawRectangle, but aborts drawing when context is done.
+// Returns *DrawError if input is invalid or context error if drawing
+// was aborted.
+func (r *Rasterizer) DrawRectangleContext(ctx context.Context, ri RectangleInfo) error {
+	const op = "Rasterizer.DrawRectangle"
+	err := validateRaster(op, ri.Buffer, ri.BufferWidth, ri.BufferHeight, ri.Shader != nil,
+		ri.Lights, ri.Indexizer, ri.ChunkBits)
+	if err != nil {
+		return err
 	}
+	r.Run()
 
 	if ri.ChunkBits == 0 {
 		ri.ChunkBits = 3
 	}
+	if r.batching {
+		ri.ChunkBits, err = r.validTileBits(op)
+		if err != nil {
+			return err
+		}
+	}
 
 	if ri.W == 0 || ri.H == 0 {
-		return
+		return nil
 	}
 
 	if ri.W < 0 {
@@ -145,80 +167,81 @@ func (r *Rasterizer) DrawRectangle(ri RectangleInfo) {
 	maxX := math.Min(x+w, float64(ri.BufferWidth-1))
 	maxY := math.Min(y+h, float64(ri.BufferHeight-1))
 
-	chunkSize := 1 << ri.ChunkBits
-	chunkSizef := float64(chunkSize)
-
-	// Steps for chunks.
-	pxCh := chunkSizef * rs.Pxs
-	pyCh := chunkSizef * rs.Pys
+	rs.ox, rs.oy = minX, minY
+	rs.pxo, rs.pyo = pxd, pyd
 
-	// Buffer offset for top-left bounding box corner.
-	boffso := int(minX) + int(minY)*ri.BufferWidth
+	chunkSizef := float64(int(1) << ri.ChunkBits)
 
+	st := r.frameStats()
+	tm := newDrawTimer(st)
+	if st != nil {
+		atomic.AddInt64(&st.primitives, 1)
+	}
 	var wg sync.WaitGroup
 
-	py := pyd
-	for y := minY; y < maxY; y += chunkSizef {
-		px := pxd
-		boffs := boffso
-		chunkHeight := maxY - y
-		if chunkHeight > chunkSizef {
-			chunkHeight = chunkSizef
-		}
+	// Chunks are aligned to the grid of chunk size, so that each chunk
+	// lies in a single tile when batching.
+	for cy := math.Floor(minY/chunkSizef) * chunkSizef; cy < maxY; cy += chunkSizef {
+		ys := math.Max(cy, minY)
+		ye := math.Min(cy+chunkSizef, maxY)
+
+		for cx := math.Floor(minX/chunkSizef) * chunkSizef; cx < maxX; cx += chunkSizef {
+			xs := math.Max(cx, minX)
+			xe := math.Min(cx+chunkSizef, maxX)
 
-		for x := minX; x < maxX; x += chunkSizef {
-			wg.Add(1)
 			chunk := RectangleChunk{
 				RectangleRasterStatic: rs,
-				Xo:                    x,
-				Yo:                    y,
-				Pxo:                   px,
-				Pyo:                   py,
-				BufferOffset:          boffs,
-				Width:                 maxX - x,
-				Height:                chunkHeight,
-				WG:                    &wg,
+				Xo:                    xs,
+				Yo:                    ys,
+				Pxo:                   pxd + (xs-minX)*rs.Pxs,
+				Pyo:                   pyd + (ys-minY)*rs.Pys,
+				BufferOffset:          int(xs) + int(ys)*ri.BufferWidth,
+				Width:                 xe - xs,
+				Height:                ye - ys,
+				stats:                 st,
 			}
-			if chunk.Width > chunkSizef {
-				chunk.Width = chunkSizef
+			if ctx.Err() != nil {
+				break
 			}
-
-			r.renderChunk(chunk)
-			px += pxCh
-			boffs += chunkSize
+			r.submit(ctx, chunk, int(xs), int(ys), &wg, &tm)
 		}
-		py += pyCh
-		boffso += ri.BufferWidth * chunkSize
 	}
-	wg.Wait()
+	r.wait(&wg, &tm)
+	return ctx.Err()
 }
 
 func (c RectangleChunk) Process() {
-	defer c.WG.Done()
-
 	maxX := c.Xo + c.Width
 	maxY := c.Yo + c.Height
 	so := RectangleShaderOpts{
 		RectangleRasterStatic: c.RectangleRasterStatic,
 	}
+	so.Lights = chunkLights(c.Lights, c.Xo, c.Yo, c.Width, c.Height)
+	var cs *chunkStats
+	if c.stats != nil {
+		cs = &chunkStats{lights: countingLights{Lights: so.Lights}}
+		so.Lights = &cs.lights
+		so.Blend.written = &cs.written
+	}
+	so.Lights, so.Indexizer = rampShading(so.Lights, so.Indexizer)
 
 	cnt := 0
-	py := c.Pyo
 	for y := c.Yo; y < maxY; y++ {
-		px := c.Pxo
+		py := c.pyo + (y-c.oy)*c.Pys
 		boffs := c.BufferOffset
 		for x := c.Xo; x < maxX; x++ {
 			so.BufferOffset = boffs
 			so.X = x
 			so.Y = y
-			so.Px = px
+			so.Px = c.pxo + (x-c.ox)*c.Pxs
 			so.Py = py
 			c.Shader(&so)
-			px += c.Pxs
 			boffs++
 			cnt++
 		}
-		py += c.Pys
 		c.BufferOffset += c.BufferWidth
 	}
+	if cs != nil {
+		cs.flush(c.stats, cnt)
+	}
 }
@@ -0,0 +1,80 @@
+package display
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Remap is a table that maps color index to another color index.
+// It's applied after indexizing, e.g. to draw the same sprite with
+// different colors (palette swap).
+type Remap [256]byte
+
+// NewRemap returns identity remap table.
+func NewRemap() *Remap {
+	var r Remap
+	for i := range r {
+		r[i] = byte(i)
+	}
+	return &r
+}
+
+// RemapIndexizer applies remap table to the result of another Indexizer.
+type RemapIndexizer struct {
+	Indexizer Indexizer
+	Remap     *Remap
+}
+
+func (ri RemapIndexizer) Indexize(intens float64, posx, posy int) byte {
+	return ri.Remap[ri.Indexizer.Indexize(intens, posx, posy)]
+}
+
+func (ri RemapIndexizer) IndexizeRamp(intens float64, ramp int, posx, posy int) byte {
+	if r, ok := ri.Indexizer.(RampIndexizer); ok {
+		return ri.Remap[r.IndexizeRamp(intens, ramp, posx, posy)]
+	}
+	return ri.Indexize(intens, posx, posy)
+}
+
+type remapsYaml struct {
+	Remaps []struct {
+		Name string      `yaml:"name"`
+		Map  map[int]int `yaml:"map"`
+	} `yaml:"remaps"`
+}
+
+// LoadRemaps loads named remap tables from YAML file to Remaps.
+// Each table lists only changed indices, the rest are kept as is:
+//
+//	remaps:
+//	- name: red-team
+//	  map:
+//	    2: 3
+//	    3: 2
+func (d *Display) LoadRemaps(fileName string) error {
+	if d.Remaps == nil {
+		d.Remaps = make(map[string]*Remap)
+	}
+	pl, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	var ry remapsYaml
+	err = yaml.Unmarshal(pl, &ry)
+	if err != nil {
+		return err
+	}
+	for _, ty := range ry.Remaps {
+		r := NewRemap()
+		for from, to := range ty.Map {
+			if from < 0 || from > 255 || to < 0 || to > 255 {
+				return fmt.Errorf("remap %v in %v: index is out of range: %v: %v", ty.Name, fileName, from, to)
+			}
+			r[from] = byte(to)
+		}
+		d.Remaps[ty.Name] = r
+	}
+	return nil
+}
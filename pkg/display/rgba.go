@@ -0,0 +1,98 @@
+package display
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// rgbaChunk is a band of image rows to convert to RGBA.
+type rgbaChunk struct {
+	iim    IndexedImage
+	dst    []byte
+	lut    *ColorLUT
+	scale  int
+	y0, y1 int
+}
+
+func (c rgbaChunk) Process() {
+	rgbaRows(c.iim, c.dst, c.lut, c.scale, c.y0, c.y1)
+}
+
+// ToRGBA converts IndexedImage to RGBA mode like IndexedImage.ToRGBA,
+// but splits the work between rasterizer workers.
+// Returns *DrawError when buffer size doesn't match.
+// Conversion is not batched, call Flush first when batching.
+func (r *Rasterizer) ToRGBA(iim IndexedImage, o ToRGBAOpts) ([]byte, error) {
+	if err := o.prepare(iim); err != nil {
+		return nil, &DrawError{Op: "Rasterizer.ToRGBA", Err: err}
+	}
+	lut := o.LUT
+	if lut == nil {
+		lut = o.Palette.LUT()
+	}
+
+	// A few bands per worker to balance the load.
+	bands := r.workerCount() * 4
+	rows := (iim.Height + bands - 1) / bands
+	if rows < 1 {
+		rows = 1
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for y := 0; y < iim.Height; y += rows {
+		c := rgbaChunk{
+			iim:   iim,
+			dst:   o.Pixels,
+			lut:   lut,
+			scale: o.Scale,
+			y0:    y,
+			y1:    y + rows,
+		}
+		if c.y1 > iim.Height {
+			c.y1 = iim.Height
+		}
+		r.renderChunk(ctx, c, &wg, nil)
+	}
+	wg.Wait()
+	return o.Pixels, nil
+}
+
+// UpdateRGBA converts Screen to RGBA buffer using Palette with color
+// cycling, transitions and fades applied. Lookup table is recalculated
+// only when resulting palette changes.
+func (d *Display) UpdateRGBA() error {
+	pal := d.effectivePalette()
+	if !bytes.Equal(d.lutPalette, pal) {
+		d.lut.Set(pal)
+		d.lutPalette = append(d.lutPalette[:0], pal...)
+	}
+	pix, err := d.Rasterizer.ToRGBA(d.Screen, ToRGBAOpts{
+		Pixels: d.RGBA,
+		LUT:    &d.lut,
+		Scale:  d.Scale,
+	})
+	if err != nil {
+		return err
+	}
+	d.RGBA = pix
+	return nil
+}
+
+// effectivePalette returns Palette with effects applied.
+func (d *Display) effectivePalette() Palette {
+	pal := d.Palette
+	if d.Cycler != nil {
+		d.effPalette = d.Cycler.Apply(d.effPalette, pal)
+		pal = d.effPalette
+	}
+	if d.paletteFX.active() {
+		if d.Cycler == nil {
+			d.effPalette = append(d.effPalette[:0], pal...)
+		}
+		d.paletteFX.apply(d.effPalette)
+		pal = d.effPalette
+	}
+	return pal
+}
@@ -0,0 +1,92 @@
+package display
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// colorizeRGBA converts image to RGBA pixel by pixel with Palette.Colorize.
+func colorizeRGBA(iim IndexedImage, p Palette, scale int) []byte {
+	w := iim.Width * scale
+	dst := make([]byte, 4*w*iim.Height*scale)
+	for y := 0; y < iim.Height*scale; y++ {
+		for x := 0; x < w; x++ {
+			p.Colorize(dst[(x+y*w)*4:], iim.Pixels[x/scale+y/scale*iim.Width])
+		}
+	}
+	return dst
+}
+
+func TestToRGBA(t *testing.T) {
+	pal := Palette{}
+	for i := 0; i < 10; i++ {
+		pal = append(pal, byte(i*20), byte(255-i*20), byte(i*7))
+	}
+	// Height 11 is prime, so bands of several rows don't divide it.
+	iim := IndexedImage{Width: 7, Height: 11, Pixels: make([]byte, 7*11)}
+	for i := range iim.Pixels {
+		// Includes 0 and indices beyond palette.
+		iim.Pixels[i] = byte(i * 3 % 14)
+	}
+
+	for _, scale := range []int{0, 1, 2, 3} {
+		want := colorizeRGBA(iim, pal, maxInt(scale, 1))
+		t.Run(fmt.Sprintf("scale=%v", scale), func(t *testing.T) {
+			got := iim.ToRGBA(ToRGBAOpts{Palette: pal, Scale: scale})
+			if !bytes.Equal(got, want) {
+				t.Errorf("IndexedImage.ToRGBA differs from Colorize")
+			}
+			got = iim.ToRGBA(ToRGBAOpts{LUT: pal.LUT(), Scale: scale, Pixels: make([]byte, len(want))})
+			if !bytes.Equal(got, want) {
+				t.Errorf("IndexedImage.ToRGBA with LUT differs from Colorize")
+			}
+
+			for _, workers := range []int{0, 1, 2, 3, 7} {
+				var r Rasterizer
+				if workers == 0 {
+					r.SingleThreaded = true
+				} else {
+					r.Workers = workers
+				}
+				got, err := r.ToRGBA(iim, ToRGBAOpts{Palette: pal, Scale: scale})
+				r.Close()
+				if err != nil {
+					t.Fatalf("workers %v: %v", workers, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("workers %v: Rasterizer.ToRGBA differs from Colorize", workers)
+				}
+			}
+		})
+	}
+}
+
+func TestToRGBAErrors(t *testing.T) {
+	iim := IndexedImage{Width: 3, Height: 2, Pixels: make([]byte, 6)}
+	tests := []struct {
+		name string
+		iim  IndexedImage
+		o    ToRGBAOpts
+	}{
+		{"small buffer", iim, ToRGBAOpts{Pixels: make([]byte, 4*6-1)}},
+		{"buffer for other scale", iim, ToRGBAOpts{Pixels: make([]byte, 4*6), Scale: 2}},
+		{"negative scale", iim, ToRGBAOpts{Scale: -1}},
+		{"invalid image", IndexedImage{Width: 3, Height: 3, Pixels: make([]byte, 6)}, ToRGBAOpts{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Rasterizer
+			r.SingleThreaded = true
+			_, err1 := r.ToRGBA(tt.iim, tt.o)
+			_, err2 := tt.iim.TryToRGBA(tt.o)
+			for _, err := range []error{err1, err2} {
+				var de *DrawError
+				if !errors.As(err, &de) || !errors.Is(err, ErrBufferSize) {
+					t.Errorf("got error %v, want *DrawError with ErrBufferSize", err)
+				}
+			}
+		})
+	}
+}
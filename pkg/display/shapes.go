@@ -0,0 +1,460 @@
+package display
+
+import (
+	"context"
+	"math"
+)
+
+// Point is a point in buffer coordinates, pixels.
+type Point struct {
+	X, Y float64
+}
+
+// ShapeOpts is a structure with options for shape drawing functions.
+type ShapeOpts struct {
+	// Fixed palette index to draw with. Used when Intensity is 0.
+	Index byte
+
+	// Greyscale intensity (1-255) that is passed through Lights
+	// and Indexizer, the same way sprite pixels are.
+	// When set, Index is ignored.
+	Intensity byte
+
+	// Line width in pixels. Default is 1.
+	// Used by lines, polylines and shape outlines.
+	Width float64
+
+	// Fill to color shape with (pattern, gradient, texture).
+	// When set, Index and Intensity are ignored.
+	Fill Fill
+}
+
+// shapeColor calculates color index for shape pixel at the given point.
+// 0 means pixel should not be drawn.
+func (o *ShapeOpts) shapeColor(l Lights, ind Indexizer, x, y int) byte {
+	if o.Fill != nil {
+		return o.Fill.FillColor(l, ind, x, y)
+	}
+	if o.Intensity == 0 {
+		return o.Index
+	}
+	return shade(l, ind, o.Intensity, x, y)
+}
+
+// shade calculates color index of the texel value (intensity) at the given
+// point using the light model and indexizer. Colored lights are supported
+// through lights and indexizer returned by rampShading.
+func shade(l Lights, ind Indexizer, val byte, x, y int) byte {
+	in := l.Light(val, x, y)
+	return ind.Indexize(in, x, y)
+}
+
+// DrawLine draws a line into the screen from (x0, y0) to (x1, y1).
+// Lines are drawn directly, bypassing the rasterizer (no blending and
+// statistics). Inside a batch, queued primitives are flushed first
+// to preserve drawing order.
+func (d *Display) DrawLine(x0, y0, x1, y1 float64, o ShapeOpts) {
+	if err := validateShape("Display.DrawLine", d.Lights, d.Indexizer, o); err != nil {
+		d.reportError(err)
+		return
+	}
+	defer d.flushBatch()()
+	DrawLine(d.Screen, d.Lights, d.Indexizer, x0, y0, x1, y1, o)
+}
+
+// DrawPolyline draws connected line segments into the screen.
+// When closed is true, the last point is connected to the first.
+// See DrawLine about batching.
+func (d *Display) DrawPolyline(pts []Point, closed bool, o ShapeOpts) {
+	if err := validateShape("Display.DrawPolyline", d.Lights, d.Indexizer, o); err != nil {
+		d.reportError(err)
+		return
+	}
+	defer d.flushBatch()()
+	DrawPolyline(d.Screen, d.Lights, d.Indexizer, pts, closed, o)
+}
+
+// validateShape checks that lights and indexizer are set when shape
+// color depends on them (Intensity or Fill is set).
+func validateShape(op string, l Lights, ind Indexizer, o ShapeOpts) error {
+	if o.Intensity == 0 && o.Fill == nil {
+		return nil
+	}
+	var err error
+	switch {
+	case l == nil:
+		err = ErrLightsNotSet
+	case ind == nil:
+		err = ErrIndexizerNotSet
+	}
+	if err != nil {
+		return &DrawError{Op: op, Err: err}
+	}
+	return nil
+}
+
+// flushBatch draws primitives queued in rasterizer batch before drawing
+// into the screen directly. Returned function restarts the batch.
+func (d *Display) flushBatch() func() {
+	if !d.Rasterizer.batching {
+		return func() {}
+	}
+	d.Rasterizer.Flush()
+	return d.Rasterizer.Begin
+}
+
+// DrawCircle draws circle outline into the screen.
+func (d *Display) DrawCircle(x, y, r float64, o ShapeOpts) {
+	d.DrawEllipse(x, y, r, r, o)
+}
+
+// FillCircle draws filled circle into the screen.
+func (d *Display) FillCircle(x, y, r float64, o ShapeOpts) {
+	d.FillEllipse(x, y, r, r, o)
+}
+
+// DrawEllipse draws axis-aligned ellipse outline into the screen.
+func (d *Display) DrawEllipse(x, y, rx, ry float64, o ShapeOpts) {
+	d.reportError(d.Rasterizer.DrawEllipse(d.ellipseInfo(x, y, rx, ry, o, false)))
+}
+
+// FillEllipse draws axis-aligned filled ellipse into the screen.
+func (d *Display) FillEllipse(x, y, rx, ry float64, o ShapeOpts) {
+	d.reportError(d.Rasterizer.DrawEllipse(d.ellipseInfo(x, y, rx, ry, o, true)))
+}
+
+// DrawPolygon draws polygon outline into the screen.
+func (d *Display) DrawPolygon(pts []Point, o ShapeOpts) {
+	d.DrawPolyline(pts, true, o)
+}
+
+// FillPolygon draws filled polygon into the screen. Polygon can be
+// convex or concave, but must not be self-intersecting.
+func (d *Display) FillPolygon(pts []Point, o ShapeOpts) {
+	d.reportError(d.Rasterizer.FillPolygon(PolygonInfo{
+		TriangleRasterInput: TriangleRasterInput{
+			Buffer:       d.Screen.Pixels,
+			BufferWidth:  d.Screen.Width,
+			BufferHeight: d.Screen.Height,
+			Lights:       d.Lights,
+			Indexizer:    d.Indexizer,
+		},
+		Points: pts,
+		Opts:   o,
+	}))
+}
+
+func (d *Display) ellipseInfo(x, y, rx, ry float64, o ShapeOpts, fill bool) EllipseInfo {
+	return EllipseInfo{
+		RectangleRasterInput: RectangleRasterInput{
+			Buffer:       d.Screen.Pixels,
+			BufferWidth:  d.Screen.Width,
+			BufferHeight: d.Screen.Height,
+			Lights:       d.Lights,
+			Indexizer:    d.Indexizer,
+		},
+		X:    x,
+		Y:    y,
+		RX:   rx,
+		RY:   ry,
+		Fill: fill,
+		Opts: o,
+	}
+}
+
+// DrawLine draws a line into the image using Bresenham algorithm.
+// Lines wider than 1 pixel are drawn with spans perpendicular
+// to the major axis of the line.
+// Lights and Indexizer are only used when o.Intensity or o.Fill is set.
+func DrawLine(iim IndexedImage, l Lights, ind Indexizer, x0, y0, x1, y1 float64, o ShapeOpts) {
+	l, ind = rampShading(l, ind)
+	o.Fill = prepareFill(o.Fill)
+	w := int(math.Floor(o.Width + 0.5))
+	if w < 1 {
+		w = 1
+	}
+	// Offset of the span start relative to line pixel.
+	so := -(w - 1) / 2
+
+	ix0, iy0 := int(math.Floor(x0)), int(math.Floor(y0))
+	ix1, iy1 := int(math.Floor(x1)), int(math.Floor(y1))
+
+	dx := ix1 - ix0
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := iy1 - iy0
+	if dy < 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if ix0 > ix1 {
+		sx = -1
+	}
+	if iy0 > iy1 {
+		sy = -1
+	}
+	steep := dy > dx
+
+	err := dx - dy
+	x, y := ix0, iy0
+	for {
+		for i := 0; i < w; i++ {
+			if steep {
+				iim.plot(l, ind, x+so+i, y, &o)
+			} else {
+				iim.plot(l, ind, x, y+so+i, &o)
+			}
+		}
+		if x == ix1 && y == iy1 {
+			break
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x += sx
+		}
+		if e2 < dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// DrawPolyline draws connected line segments into the image.
+// When closed is true, the last point is connected to the first.
+func DrawPolyline(iim IndexedImage, l Lights, ind Indexizer, pts []Point, closed bool, o ShapeOpts) {
+	for i := 1; i < len(pts); i++ {
+		DrawLine(iim, l, ind, pts[i-1].X, pts[i-1].Y, pts[i].X, pts[i].Y, o)
+	}
+	if closed && len(pts) > 2 {
+		n := len(pts) - 1
+		DrawLine(iim, l, ind, pts[n].X, pts[n].Y, pts[0].X, pts[0].Y, o)
+	}
+}
+
+// plot sets a single pixel of the image, if it's within image bounds.
+func (iim IndexedImage) plot(l Lights, ind Indexizer, x, y int, o *ShapeOpts) {
+	if x < 0 || y < 0 || x >= iim.Width || y >= iim.Height {
+		return
+	}
+	col := o.shapeColor(l, ind, x, y)
+	if col == 0 {
+		return
+	}
+	iim.Pixels[x+y*iim.Width] = col
+}
+
+// EllipseInfo input data to DrawEllipse call.
+type EllipseInfo struct {
+	RectangleRasterInput
+
+	// Center, pixels.
+	X, Y float64
+
+	// Radii, pixels.
+	RX, RY float64
+
+	// Draw filled ellipse instead of outline.
+	Fill bool
+
+	// Shape color and outline width.
+	Opts ShapeOpts
+}
+
+// DrawEllipse draws axis-aligned ellipse (filled or outline) using rectangle
+// rasterizer. Shader field of the input is ignored.
+// Returns *DrawError if input is invalid.
+func (r *Rasterizer) DrawEllipse(ei EllipseInfo) error {
+	if ei.RX < 0 {
+		ei.RX = -ei.RX
+	}
+	if ei.RY < 0 {
+		ei.RY = -ei.RY
+	}
+	if ei.RX == 0 || ei.RY == 0 {
+		return nil
+	}
+	w := ei.Opts.Width
+	if w <= 0 {
+		w = 1
+	}
+	ei.Shader = RectShaderEllipse(ei.X, ei.Y, ei.RX, ei.RY, w, ei.Fill, ei.Opts)
+	return r.DrawRectangleContext(context.Background(), RectangleInfo{
+		RectangleRasterInput: ei.RectangleRasterInput,
+		X:                    ei.X - ei.RX - 0.5,
+		Y:                    ei.Y - ei.RY - 0.5,
+		W:                    2*ei.RX + 2,
+		H:                    2*ei.RY + 2,
+	})
+}
+
+// RectShaderEllipse returns rectangle shader that draws an ellipse
+// with center (cx, cy) and radii (rx, ry) within the rectangle.
+// When fill is false, only outline of width w is drawn.
+func RectShaderEllipse(cx, cy, rx, ry, w float64, fill bool, so ShapeOpts) func(o *RectangleShaderOpts) {
+	so.Fill = prepareFill(so.Fill)
+	irx2 := 1 / (rx * rx)
+	iry2 := 1 / (ry * ry)
+	// Inner ellipse for outlines.
+	rxi, ryi := rx-w, ry-w
+	var irxi2, iryi2 float64
+	hollow := !fill && rxi > 0 && ryi > 0
+	if hollow {
+		irxi2 = 1 / (rxi * rxi)
+		iryi2 = 1 / (ryi * ryi)
+	}
+
+	return func(o *RectangleShaderOpts) {
+		// Sample in pixel center.
+		dx := o.X + 0.5 - cx
+		dy := o.Y + 0.5 - cy
+		dx2, dy2 := dx*dx, dy*dy
+		if dx2*irx2+dy2*iry2 > 1 {
+			return
+		}
+		if hollow && dx2*irxi2+dy2*iryi2 < 1 {
+			return
+		}
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		col := so.shapeColor(o.Lights, o.Indexizer, x, y)
+		if col == 0 {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, col, x, y)
+	}
+}
+
+// PolygonInfo input data to FillPolygon call.
+type PolygonInfo struct {
+	TriangleRasterInput
+
+	// Polygon vertices in either winding order.
+	Points []Point
+
+	// Shape color.
+	Opts ShapeOpts
+}
+
+// FillPolygon triangulates polygon and draws it using triangle rasterizer.
+// Shader field of the input is ignored.
+// Returns *DrawError if input is invalid.
+func (r *Rasterizer) FillPolygon(pi PolygonInfo) error {
+	pi.Shader = TriShaderShape(pi.Opts)
+	tris := Triangulate(pi.Points)
+	for i := 0; i+2 < len(tris); i += 3 {
+		p0, p1, p2 := pi.Points[tris[i]], pi.Points[tris[i+1]], pi.Points[tris[i+2]]
+		err := r.DrawTriangleContext(context.Background(), TriangleInfo{
+			TriangleRasterInput: pi.TriangleRasterInput,
+			X0:                  p0.X,
+			Y0:                  p0.Y,
+			X1:                  p1.X,
+			Y1:                  p1.Y,
+			X2:                  p2.X,
+			Y2:                  p2.Y,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TriShaderShape returns triangle shader that fills triangle with shape color.
+func TriShaderShape(so ShapeOpts) func(o *TriangleShaderOpts) {
+	so.Fill = prepareFill(so.Fill)
+	return func(o *TriangleShaderOpts) {
+		x, y := int(o.X), int(o.Y)
+		if !o.Blend.Visible(x, y) {
+			return
+		}
+		col := so.shapeColor(o.Lights, o.Indexizer, x, y)
+		if col == 0 {
+			return
+		}
+		o.Blend.Put(o.Buffer, o.BufferOffset, col, x, y)
+	}
+}
+
+// Triangulate splits simple polygon (convex or concave) into triangles
+// using ear clipping. Returns indices of points, 3 per triangle.
+// Self-intersecting polygons produce incomplete result.
+func Triangulate(pts []Point) []int {
+	n := len(pts)
+	if n < 3 {
+		return nil
+	}
+
+	// Ear clipping expects the same orientation DrawTriangle fills
+	// (positive area).
+	idx := make([]int, n)
+	if polygonArea(pts) > 0 {
+		for i := range idx {
+			idx[i] = i
+		}
+	} else {
+		for i := range idx {
+			idx[i] = n - 1 - i
+		}
+	}
+
+	tris := make([]int, 0, (n-2)*3)
+	// Guard against infinite loop on degenerate input.
+	for guard := 2 * n; len(idx) > 3 && guard > 0; guard-- {
+		clipped := false
+		for i := 0; i < len(idx); i++ {
+			ip := idx[(i+len(idx)-1)%len(idx)]
+			ic := idx[i]
+			in := idx[(i+1)%len(idx)]
+			if !isEar(pts, idx, ip, ic, in) {
+				continue
+			}
+			tris = append(tris, ip, ic, in)
+			idx = append(idx[:i], idx[i+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			break
+		}
+	}
+	if len(idx) == 3 {
+		tris = append(tris, idx[0], idx[1], idx[2])
+	}
+	return tris
+}
+
+// polygonArea returns doubled signed area of the polygon.
+func polygonArea(pts []Point) float64 {
+	var a float64
+	for i := range pts {
+		j := (i + 1) % len(pts)
+		a += pts[i].X*pts[j].Y - pts[j].X*pts[i].Y
+	}
+	return a
+}
+
+// isEar checks whether vertex c with neighbours p and n is an ear
+// of the polygon represented by remaining indices idx.
+func isEar(pts []Point, idx []int, p, c, n int) bool {
+	a, b, cc := pts[p], pts[c], pts[n]
+	// Reflex or degenerate vertex.
+	if edgeFunc(a.X, a.Y, b.X, b.Y, cc.X, cc.Y) <= 0 {
+		return false
+	}
+	for _, i := range idx {
+		if i == p || i == c || i == n {
+			continue
+		}
+		q := pts[i]
+		if edgeFunc(a.X, a.Y, b.X, b.Y, q.X, q.Y) >= 0 &&
+			edgeFunc(b.X, b.Y, cc.X, cc.Y, q.X, q.Y) >= 0 &&
+			edgeFunc(cc.X, cc.Y, a.X, a.Y, q.X, q.Y) >= 0 {
+			return false
+		}
+	}
+	return true
+}
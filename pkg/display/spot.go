@@ -0,0 +1,86 @@
+package display
+
+import "math"
+
+// Directed is an optional interface of Tracked object that faces some
+// direction, e.g. a character with a flashlight or a car.
+type Directed interface {
+	// Direction returns facing angle in radians. 0 points along X axis,
+	// positive angles turn towards Y axis (clockwise on screen).
+	Direction() float64
+}
+
+// SpotSource is a point light source with a cone area (spot light), which
+// tracks an object position, size, facing direction and alive status.
+// Along the cone direction it's lit the same as CircleSource.
+// Light is full inside inner cone and smoothly fades to none at outer cone.
+type SpotSource struct {
+	Tracked    Tracked
+	Intensity  float64
+	FallRadius float64
+
+	// Direction in radians, used when Tracked doesn't implement Directed.
+	Angle float64
+
+	// Half-angles of inner and outer cones in radians.
+	// Outer less than Inner is treated as Inner (hard edge).
+	Inner, Outer float64
+
+	// Attenuation curve. Default is FalloffDefault.
+	Falloff Falloff
+	// Light is exactly zero beyond this distance. 0 is unlimited.
+	MaxRadius float64
+
+	// Height of light above the screen for normal mapping.
+	// Default is FallRadius.
+	Height float64
+}
+
+// TrackSpot adds a spot light source tied to an object that conforms
+// a Tracked interface. Object should also implement Directed, otherwise
+// light points along X axis.
+func (l *LightSet) TrackSpot(t Tracked, intens, rfall, inner, outer float64) {
+	ss := &SpotSource{
+		Tracked:    t,
+		Intensity:  intens,
+		FallRadius: rfall,
+		Inner:      inner,
+		Outer:      outer,
+	}
+	l.add(ss)
+}
+
+func (s *SpotSource) OffsetScale(posx, posy int) (offs float64, scale float64) {
+	x, y := s.Tracked.Pos()
+	dx := float64(posx) - x
+	dy := float64(posy) - y
+	d2 := dx*dx + dy*dy
+	fr2 := s.FallRadius * s.FallRadius * s.Tracked.SizeMod()
+	scale = s.Intensity * attenuate(s.Falloff, d2, fr2, s.MaxRadius)
+	if d2 == 0 || scale == 0 {
+		return 0, scale
+	}
+	return 0, scale * s.cone(math.Atan2(dy, dx))
+}
+
+// cone returns light factor (0-1) for the angle of direction to the point.
+func (s *SpotSource) cone(a float64) float64 {
+	dir := s.Angle
+	if d, ok := s.Tracked.(Directed); ok {
+		dir = d.Direction()
+	}
+	// Angle between spot direction and the point in range 0-Pi.
+	diff := math.Abs(math.Remainder(a-dir, 2*math.Pi))
+	switch {
+	case diff <= s.Inner:
+		return 1
+	case diff >= s.Outer:
+		return 0
+	}
+	t := (s.Outer - diff) / (s.Outer - s.Inner)
+	return t * t * (3 - 2*t)
+}
+
+func (s *SpotSource) Alive() bool {
+	return s.Tracked.Alive()
+}
@@ -18,12 +18,42 @@ type Sprite struct {
 	X, Y             int
 	Width, Height    int
 	XOrigin, YOrigin int
+
+	// Atlas pixels are color indices that are drawn as is,
+	// without lights and indexizer (see QuantizeImage).
+	Unlit bool
+
+	// Normal map of the atlas, optional.
+	Normals *NormalMap
+
+	// Emissive mask of the atlas, optional. Pixels that are set in mask
+	// are drawn at full intensity regardless of lights.
+	Emissive *IndexedImage
+
+	// Light emitted by sprite, optional.
+	Glow *Glow
 }
 
 type altasYaml struct {
 	Name    string        `yaml:"name"`
 	File    string        `yaml:"file"`
 	Sprites []spritesYaml `yaml:"sprites"`
+
+	// Quantize image to Display.Palette and draw sprites unlit.
+	Unlit bool `yaml:"unlit"`
+	// Dithering of unlit atlas: "none" (default), "floyd-steinberg"
+	// or "ordered".
+	Dither string `yaml:"dither"`
+
+	// Normal map image of the same size as atlas image, optional.
+	Normals string `yaml:"normals"`
+
+	// Emissive mask image of the same size as atlas image, optional.
+	// Pixels that are not transparent or black are emissive.
+	Emissive string `yaml:"emissive"`
+
+	// Light emitted by atlas sprites, optional.
+	Glow *Glow `yaml:"glow"`
 }
 
 type spritesYaml struct {
@@ -58,7 +88,53 @@ func (d *Display) LoadAtlas(fileName string) error {
 	if err != nil {
 		return fmt.Errorf("failed to open image %v: %v", fileName, err)
 	}
-	tex := IndexedImageFromImage(im, FromImageOpts{})
+	var tex IndexedImage
+	if atl.Unlit {
+		if d.Palette.ColorsNumber() == 0 {
+			return fmt.Errorf("atlas %v is unlit, but palette is not set", fileName)
+		}
+		qo := QuantizeOpts{Palette: d.Palette}
+		switch atl.Dither {
+		case "", "none":
+		case "floyd-steinberg":
+			qo.Dither = QuantizeFloydSteinberg
+		case "ordered":
+			qo.Dither = QuantizeOrdered
+		default:
+			return fmt.Errorf("atlas %v: unknown dither %q", fileName, atl.Dither)
+		}
+		tex = QuantizeImage(im, qo)
+	} else {
+		tex = IndexedImageFromImage(im, FromImageOpts{})
+	}
+
+	var nm *NormalMap
+	if atl.Normals != "" {
+		nim, err := LoadImage(strings.ReplaceAll(atl.Normals, `\`, `/`))
+		if err != nil {
+			return fmt.Errorf("failed to open normal map %v: %v", fileName, err)
+		}
+		n := NormalMapFromImage(nim)
+		if n.Width != tex.Width || n.Height != tex.Height {
+			return fmt.Errorf("normal map %v: size %vx%v doesn't match atlas size %vx%v",
+				fileName, n.Width, n.Height, tex.Width, tex.Height)
+		}
+		nm = &n
+	}
+
+	var em *IndexedImage
+	if atl.Emissive != "" {
+		eim, err := LoadImage(strings.ReplaceAll(atl.Emissive, `\`, `/`))
+		if err != nil {
+			return fmt.Errorf("failed to open emissive mask %v: %v", fileName, err)
+		}
+		e := IndexedImageFromImage(eim, FromImageOpts{})
+		if e.Width != tex.Width || e.Height != tex.Height {
+			return fmt.Errorf("emissive mask %v: size %vx%v doesn't match atlas size %vx%v",
+				fileName, e.Width, e.Height, tex.Width, tex.Height)
+		}
+		em = &e
+	}
 
 	if atl.Name == "" {
 		p1 := strings.Split(atl.File, "/")
@@ -67,24 +143,30 @@ func (d *Display) LoadAtlas(fileName string) error {
 	}
 	d.Atlases[atl.Name] = &tex
 	for _, s := range atl.Sprites {
-		d.addSprites(&tex, &s, atl.Name)
+		tmpl := Sprite{
+			Atlas:    &tex,
+			Unlit:    atl.Unlit,
+			Normals:  nm,
+			Emissive: em,
+			Glow:     atl.Glow,
+		}
+		d.addSprites(tmpl, &s, atl.Name)
 	}
 
 	return nil
 }
 
-func (d *Display) addSprites(atl *IndexedImage, spr *spritesYaml, pref string) {
+// addSprites adds sprites that share atlas and its properties
+// from the template.
+func (d *Display) addSprites(tmpl Sprite, spr *spritesYaml, pref string) {
+	atl := tmpl.Atlas
 	x, y := spr.XOffs, spr.YOffs
 	for _, n := range spr.Names {
-		s := &Sprite{
-			Atlas:   atl,
-			X:       x,
-			Y:       y,
-			Width:   spr.Width,
-			Height:  spr.Height,
-			XOrigin: spr.XOrig,
-			YOrigin: spr.YOrig,
-		}
+		s := &Sprite{}
+		*s = tmpl
+		s.X, s.Y = x, y
+		s.Width, s.Height = spr.Width, spr.Height
+		s.XOrigin, s.YOrigin = spr.XOrig, spr.YOrig
 		var last bool
 		x += spr.Width
 		if x >= atl.Width {
@@ -0,0 +1,260 @@
+package display
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// FrameStats is a rasterizer performance statistics collected since
+// the last Rasterizer.ResetStats call (typically once per frame).
+type FrameStats struct {
+	// Number of drawn primitives (rectangles and triangles).
+	Primitives int
+	// Number of chunks dispatched to workers.
+	Chunks int
+
+	// Number of pixels covered by primitives and passed to shaders.
+	Pixels int
+	// Number of pixels written to buffer with Blend.Put. Shaders that
+	// write to the buffer directly are not counted, so with custom shaders
+	// only Pixels is accurate.
+	PixelsShaded int
+	// Number of pixels that were not written: no color (index 0),
+	// transparent or left as is by blend mode (e.g. BlendDarken over
+	// empty pixel).
+	PixelsSkipped int
+
+	// Number of Lights.Light calls.
+	LightEvals int
+
+	// Time spent by drawing calls to split primitives into chunks.
+	Setup time.Duration
+	// Time spent by drawing calls waiting for workers, including waiting
+	// for a free worker to take a chunk. In single-threaded mode it's
+	// the time chunks are processed.
+	Wait time.Duration
+	// Time passed since statistics reset.
+	Elapsed time.Duration
+
+	// Time each worker spent processing chunks.
+	WorkerBusy []time.Duration
+}
+
+// Utilization returns average worker utilization (0-1): fraction of elapsed
+// time workers spent processing chunks.
+func (s FrameStats) Utilization() float64 {
+	if s.Elapsed <= 0 || len(s.WorkerBusy) == 0 {
+		return 0
+	}
+	var busy time.Duration
+	for _, b := range s.WorkerBusy {
+		busy += b
+	}
+	return float64(busy) / float64(s.Elapsed) / float64(len(s.WorkerBusy))
+}
+
+// rasterStats accumulates statistics from drawing calls and workers.
+type rasterStats struct {
+	primitives int64
+	chunks     int64
+	pixels     int64
+	shaded     int64
+	lightEvals int64
+	setup      int64
+	wait       int64
+
+	busy  []int64
+	start time.Time
+}
+
+// Stats returns statistics collected since the last ResetStats call.
+// Statistics are collected only when CollectStats is set.
+func (r *Rasterizer) Stats() FrameStats {
+	st := r.stats
+	if st == nil {
+		return FrameStats{}
+	}
+	fs := FrameStats{
+		Primitives: int(atomic.LoadInt64(&st.primitives)),
+		Chunks:     int(atomic.LoadInt64(&st.chunks)),
+		Pixels:     int(atomic.LoadInt64(&st.pixels)),
+		LightEvals: int(atomic.LoadInt64(&st.lightEvals)),
+		Setup:      time.Duration(atomic.LoadInt64(&st.setup)),
+		Wait:       time.Duration(atomic.LoadInt64(&st.wait)),
+		Elapsed:    time.Since(st.start),
+		WorkerBusy: make([]time.Duration, len(st.busy)),
+	}
+	fs.PixelsShaded = int(atomic.LoadInt64(&st.shaded))
+	fs.PixelsSkipped = fs.Pixels - fs.PixelsShaded
+	for i := range st.busy {
+		fs.WorkerBusy[i] = time.Duration(atomic.LoadInt64(&st.busy[i]))
+	}
+	return fs
+}
+
+// ResetStats starts collecting statistics for a new frame.
+func (r *Rasterizer) ResetStats() {
+	r.stats = &rasterStats{
+		busy:  make([]int64, r.workerCount()),
+		start: time.Now(),
+	}
+}
+
+// frameStats returns current statistics accumulator or nil when statistics
+// are not collected.
+func (r *Rasterizer) frameStats() *rasterStats {
+	if !r.CollectStats {
+		return nil
+	}
+	if r.stats == nil || len(r.stats.busy) != r.workerCount() {
+		r.ResetStats()
+	}
+	return r.stats
+}
+
+func (st *rasterStats) addSince(v *int64, start time.Time) {
+	atomic.AddInt64(v, int64(time.Since(start)))
+}
+
+// chunkStats counts chunk pixels. It's local to chunk to avoid contention.
+type chunkStats struct {
+	lights  countingLights
+	written int
+}
+
+func (cs *chunkStats) flush(st *rasterStats, pixels int) {
+	atomic.AddInt64(&st.pixels, int64(pixels))
+	atomic.AddInt64(&st.shaded, int64(cs.written))
+	atomic.AddInt64(&st.lightEvals, int64(cs.lights.evals))
+}
+
+// countingLights counts calls to Lights.
+type countingLights struct {
+	Lights
+	evals int
+}
+
+func (l *countingLights) Light(val byte, posx, posy int) float64 {
+	l.evals++
+	return l.Lights.Light(val, posx, posy)
+}
+
+// DrawStats draws statistics overlay into the screen with top-left corner
+// at (x, y) using fg color index for text and bg color index for
+// background (0 for no background). Worker utilization is drawn as bars.
+// When batching, call it after Rasterizer.Flush.
+func (d *Display) DrawStats(s FrameStats, x, y int, fg, bg byte) {
+	ms := func(t time.Duration) string {
+		return fmt.Sprintf("%.2fMS", float64(t)/float64(time.Millisecond))
+	}
+	lines := []string{
+		fmt.Sprintf("PRIM  %d", s.Primitives),
+		fmt.Sprintf("CHUNK %d", s.Chunks),
+		fmt.Sprintf("SHADE %d", s.PixelsShaded),
+		fmt.Sprintf("SKIP  %d", s.PixelsSkipped),
+		fmt.Sprintf("LIGHT %d", s.LightEvals),
+		"SETUP " + ms(s.Setup),
+		"WAIT  " + ms(s.Wait),
+		fmt.Sprintf("UTIL  %d%%", int(s.Utilization()*100)),
+	}
+
+	const barWidth = 40
+	w := barWidth
+	for _, l := range lines {
+		if lw := len(l) * debugGlyphWidth; lw > w {
+			w = lw
+		}
+	}
+	h := len(lines)*debugGlyphHeight + len(s.WorkerBusy)*2
+
+	if bg != 0 {
+		d.Screen.fillRect(x-1, y-1, w+1, h+1, bg)
+	}
+	for i, l := range lines {
+		d.Screen.drawDebugText(x, y+i*debugGlyphHeight, l, fg)
+	}
+	by := y + len(lines)*debugGlyphHeight
+	for i, b := range s.WorkerBusy {
+		bw := 0
+		if s.Elapsed > 0 {
+			bw = int(float64(barWidth) * float64(b) / float64(s.Elapsed))
+		}
+		if bw > barWidth {
+			bw = barWidth
+		}
+		d.Screen.fillRect(x, by+i*2, bw, 1, fg)
+	}
+}
+
+// fillRect fills the rectangle with the color clipping it to image bounds.
+func (iim IndexedImage) fillRect(x, y, w, h int, col byte) {
+	for yy := y; yy < y+h; yy++ {
+		if yy < 0 || yy >= iim.Height {
+			continue
+		}
+		for xx := x; xx < x+w; xx++ {
+			if xx < 0 || xx >= iim.Width {
+				continue
+			}
+			iim.Pixels[xx+yy*iim.Width] = col
+		}
+	}
+}
+
+// Size of debug font cell, including spacing.
+const (
+	debugGlyphWidth  = 4
+	debugGlyphHeight = 6
+)
+
+// drawDebugText draws text with 3x5 debug font. Unknown characters
+// are drawn as spaces.
+func (iim IndexedImage) drawDebugText(x, y int, s string, col byte) {
+	for _, ch := range s {
+		g := debugFont[ch]
+		for row := 0; row < 5; row++ {
+			for bit := 0; bit < 3; bit++ {
+				if g[row]&(4>>uint(bit)) != 0 {
+					iim.fillRect(x+bit, y+row, 1, 1, col)
+				}
+			}
+		}
+		x += debugGlyphWidth
+	}
+}
+
+// debugFont is a minimal 3x5 font. Each byte is a row, 3 lower bits
+// are pixels, the highest is the leftmost.
+var debugFont = map[rune][5]byte{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'A': {2, 5, 7, 5, 5},
+	'C': {7, 4, 4, 4, 7},
+	'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7},
+	'G': {7, 4, 5, 5, 7},
+	'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7},
+	'K': {5, 5, 6, 5, 5},
+	'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5},
+	'N': {6, 5, 5, 5, 5},
+	'P': {6, 5, 6, 4, 4},
+	'R': {6, 5, 6, 5, 5},
+	'S': {3, 4, 2, 1, 6},
+	'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7},
+	'W': {5, 5, 7, 7, 5},
+	'%': {5, 1, 2, 4, 5},
+	'.': {0, 0, 0, 0, 2},
+	'-': {0, 0, 7, 0, 0},
+}
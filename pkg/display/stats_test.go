@@ -0,0 +1,34 @@
+package display
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStatsTimings(t *testing.T) {
+	for _, workers := range []int{0, 3} {
+		t.Run(fmt.Sprintf("workers=%v", workers), func(t *testing.T) {
+			d := newTestDisplay(workers)
+			defer d.Close()
+			d.Rasterizer.CollectStats = true
+			d.Rasterizer.ResetStats()
+			for i := 0; i < 10; i++ {
+				drawTestScene(d, true)
+			}
+			s := d.Rasterizer.Stats()
+
+			if s.Primitives == 0 || s.Chunks == 0 || s.Pixels == 0 {
+				t.Fatalf("nothing is counted: %+v", s)
+			}
+			if s.Setup < 0 || s.Wait < 0 {
+				t.Errorf("negative time: setup %v, wait %v", s.Setup, s.Wait)
+			}
+			if s.Setup+s.Wait > s.Elapsed {
+				t.Errorf("setup %v + wait %v > elapsed %v", s.Setup, s.Wait, s.Elapsed)
+			}
+			if workers == 0 && s.Setup+s.WorkerBusy[0] > s.Elapsed {
+				t.Errorf("setup %v + busy %v > elapsed %v", s.Setup, s.WorkerBusy[0], s.Elapsed)
+			}
+		})
+	}
+}
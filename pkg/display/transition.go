@@ -0,0 +1,147 @@
+package display
+
+import (
+	"fmt"
+)
+
+// Colors for palette fades.
+var (
+	Black = [3]byte{0x00, 0x00, 0x00}
+	White = [3]byte{0xff, 0xff, 0xff}
+)
+
+// paletteEffects holds state of palette transition and fade effects.
+// Effects are applied when converting to RGBA, so the lit indexed image
+// is unaffected.
+type paletteEffects struct {
+	// Crossfade from palette to Display.Palette.
+	from     Palette
+	progress float64
+	duration float64
+
+	// Tint color mixed into palette with amount (0-1) that changes
+	// towards target with speed (per second).
+	tint   [3]byte
+	amount float64
+	target float64
+	speed  float64
+}
+
+func (fx *paletteEffects) update(dt float64) {
+	if fx.from != nil {
+		fx.progress += dt
+		if fx.progress >= fx.duration {
+			fx.from = nil
+		}
+	}
+	if fx.amount < fx.target {
+		fx.amount += fx.speed * dt
+		if fx.amount > fx.target {
+			fx.amount = fx.target
+		}
+	} else if fx.amount > fx.target {
+		fx.amount -= fx.speed * dt
+		if fx.amount < fx.target {
+			fx.amount = fx.target
+		}
+	}
+}
+
+// apply applies effects to the palette in place.
+func (fx *paletteEffects) apply(p Palette) {
+	if fx.from != nil {
+		t := fx.progress / fx.duration
+		for i := range p {
+			var f byte
+			if i < len(fx.from) {
+				f = fx.from[i]
+			}
+			p[i] = mixByte(f, p[i], t)
+		}
+	}
+	if fx.amount > 0 {
+		for i := range p {
+			p[i] = mixByte(p[i], fx.tint[i%3], fx.amount)
+		}
+	}
+}
+
+// active checks whether any effect changes palette.
+func (fx *paletteEffects) active() bool {
+	return fx.from != nil || fx.amount > 0
+}
+
+// mixByte interpolates between a and b, t is in the range 0-1.
+func mixByte(a, b byte, t float64) byte {
+	return byte(float64(a)*(1-t) + float64(b)*t + 0.5)
+}
+
+// TransitionPalette sets Display.Palette to p crossfading RGBA output
+// from the current palette for duration seconds.
+func (d *Display) TransitionPalette(p Palette, duration float64) {
+	if duration > 0 {
+		// Start from what is currently on the screen, including
+		// unfinished transition.
+		from := append(Palette(nil), d.effectivePalette()...)
+		d.paletteFX.from = from
+		d.paletteFX.progress = 0
+		d.paletteFX.duration = duration
+	} else {
+		d.paletteFX.from = nil
+	}
+	d.Palette = p
+}
+
+// SetPalette switches Display.Palette and Cycler to a palette loaded with
+// LoadPalette or one of Palettes2Bit, crossfading for duration seconds
+// (0 switches immediately). Use it to change palette per level.
+func (d *Display) SetPalette(name string, duration float64) error {
+	p, ok := d.Palettes[name]
+	if !ok {
+		p, ok = Palettes2Bit[name]
+	}
+	if !ok {
+		return fmt.Errorf("palette %v is not found", name)
+	}
+	d.Cycler = nil
+	if cycles := d.PaletteCycles[name]; len(cycles) > 0 {
+		d.Cycler = &PaletteCycler{Cycles: cycles}
+	}
+	d.TransitionPalette(p, duration)
+	return nil
+}
+
+// FadeTo fades RGBA output to the color (e.g. Black or White)
+// for duration seconds. Screen stays filled with the color until FadeIn.
+func (d *Display) FadeTo(c [3]byte, duration float64) {
+	d.paletteFX.tint = c
+	d.fade(1, duration)
+}
+
+// FadeIn fades RGBA output from the current fade color back to palette
+// colors for duration seconds.
+func (d *Display) FadeIn(duration float64) {
+	d.fade(0, duration)
+}
+
+// Flash fills RGBA output with the color and fades it back to palette
+// colors for duration seconds.
+func (d *Display) Flash(c [3]byte, duration float64) {
+	d.paletteFX.tint = c
+	d.paletteFX.amount = 1
+	d.fade(0, duration)
+}
+
+func (d *Display) fade(target, duration float64) {
+	fx := &d.paletteFX
+	fx.target = target
+	if duration <= 0 {
+		fx.amount = target
+		return
+	}
+	diff := target - fx.amount
+	if diff < 0 {
+		diff = -diff
+	}
+	fx.speed = diff / duration
+}
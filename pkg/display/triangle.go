@@ -1,8 +1,10 @@
 package display
 
 import (
+	"context"
 	"math"
 	"sync"
+	"sync/atomic"
 )
 
 // TriangleRasterInput part of options that is passed to rasterizer workers.
@@ -27,6 +29,9 @@ type TriangleRasterInput struct {
 	// Object to convert to index color.
 	Indexizer Indexizer
 
+	// Transparency and blend mode. Zero value draws opaque pixels.
+	Blend Blend
+
 	// Any other data to pass to shader.
 	Extra interface{}
 }
@@ -42,6 +47,12 @@ type TriangleRasterStatic struct {
 
 	// 1 / area.
 	InvArea float64
+
+	// Top-left pixel of the clipped bounding box and its barycentric
+	// coords. Coords of each pixel are calculated from them, so they don't
+	// depend on chunk origin (e.g. when batching).
+	ox, oy        float64
+	w0o, w1o, w2o float64
 }
 
 // TriangleShaderOpts options for shader.
@@ -86,28 +97,39 @@ type TriangleChunk struct {
 	// Chunk height in pixels (can be less than chunk size).
 	Height float64
 
-	WG *sync.WaitGroup
+	stats *rasterStats
 }
 
+// DrawTriangle draws triangle using its shader. Call blocks until the triangle
+// is drawn (or queued, see Begin).
+// Panics if input is invalid, use DrawTriangleContext to get an error instead.
 func (r *Rasterizer) DrawTriangle(ti TriangleInfo) {
-	r.Run()
-
-	if ti.Buffer == nil {
-		panic("Rasterizer.DrawTriangle: buffer is not set")
-	}
-	if ti.Shader == nil {
-		panic("Rasterizer.DrawTriangle: shader is not set")
+	if err := r.DrawTriangleContext(context.Background(), ti); err != nil {
+		panic(err)
 	}
-	if ti.Lights == nil {
-		panic("Rasterizer.DrawTriangle: lights are not set")
-	}
-	if ti.Indexizer == nil {
-		panic("Rasterizer.DrawTriangle: indexizer is not set")
+}
+
+// DrawTriangleContext is like DrawTriangle, but aborts drawing when context is done.
+// Returns *DrawError if input is invalid or context error if drawing
+// was aborted.
+func (r *Rasterizer) DrawTriangleContext(ctx context.Context, ti TriangleInfo) error {
+	const op = "Rasterizer.DrawTriangle"
+	err := validateRaster(op, ti.Buffer, ti.BufferWidth, ti.BufferHeight, ti.Shader != nil,
+		ti.Lights, ti.Indexizer, ti.ChunkBits)
+	if err != nil {
+		return err
 	}
+	r.Run()
 
 	if ti.ChunkBits == 0 {
 		ti.ChunkBits = 3
 	}
+	if r.batching {
+		ti.ChunkBits, err = r.validTileBits(op)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Structure that will contain values precomputed for all pixels.
 	rs := TriangleRasterStatic{
@@ -136,12 +158,11 @@ func (r *Rasterizer) DrawTriangle(ti TriangleInfo) {
 	maxX = math.Min(maxX, float64(ti.BufferWidth-1))
 	maxY = math.Min(maxY, float64(ti.BufferHeight-1))
 
-	chunkSize := 1 << ti.ChunkBits
-	chunkSizef := float64(chunkSize)
+	chunkSizef := float64(int(1) << ti.ChunkBits)
 
 	area := edgeFunc(ti.X0, ti.Y0, ti.X1, ti.Y1, ti.X2, ti.Y2)
 	if area == 0 {
-		return
+		return nil
 	}
 	// Barycentric coords of origin (top-left corner of box surrounding the triangle).
 	w0o := edgeFunc(ti.X1, ti.Y1, ti.X2, ti.Y2, minX, minY)
@@ -160,74 +181,73 @@ func (r *Rasterizer) DrawTriangle(ti TriangleInfo) {
 	}
 
 	rs.InvArea = 1 / area
+	rs.ox, rs.oy = minX, minY
+	rs.w0o, rs.w1o, rs.w2o = w0o, w1o, w2o
 
-	// Barycentric steps for chunks.
-	w0xStep := rs.A12 * chunkSizef
-	w1xStep := rs.A20 * chunkSizef
-	w2xStep := rs.A01 * chunkSizef
-	w0yStep := rs.B12 * chunkSizef
-	w1yStep := rs.B20 * chunkSizef
-	w2yStep := rs.B01 * chunkSizef
-
-	// Buffer offset for top-left bounding box corner.
-	boffso := int(minX) + int(minY)*ti.BufferWidth
-
+	st := r.frameStats()
+	tm := newDrawTimer(st)
+	if st != nil {
+		atomic.AddInt64(&st.primitives, 1)
+	}
 	var wg sync.WaitGroup
 
-	for y := minY; y < maxY; y += chunkSizef {
-		w0, w1, w2 := w0o, w1o, w2o
-		boffs := boffso
-		chunkHeight := maxY - y
-		if chunkHeight > chunkSizef {
-			chunkHeight = chunkSizef
-		}
+	// Chunks are aligned to the grid of chunk size, so that each chunk
+	// lies in a single tile when batching.
+	for cy := math.Floor(minY/chunkSizef) * chunkSizef; cy < maxY; cy += chunkSizef {
+		ys := math.Max(cy, minY)
+		ye := math.Min(cy+chunkSizef, maxY)
 
-		for x := minX; x < maxX; x += chunkSizef {
-			wg.Add(1)
+		for cx := math.Floor(minX/chunkSizef) * chunkSizef; cx < maxX; cx += chunkSizef {
+			xs := math.Max(cx, minX)
+			xe := math.Min(cx+chunkSizef, maxX)
+
+			// Barycentric coords of chunk origin.
+			dx, dy := xs-minX, ys-minY
 			chunk := TriangleChunk{
 				TriangleRasterStatic: rs,
-				Xo:                   x,
-				Yo:                   y,
-				W0o:                  w0,
-				W1o:                  w1,
-				W2o:                  w2,
-				BufferOffset:         boffs,
-				Width:                maxX - x,
-				Height:               chunkHeight,
-				WG:                   &wg,
+				Xo:                   xs,
+				Yo:                   ys,
+				W0o:                  w0o + dx*rs.A12 + dy*rs.B12,
+				W1o:                  w1o + dx*rs.A20 + dy*rs.B20,
+				W2o:                  w2o + dx*rs.A01 + dy*rs.B01,
+				BufferOffset:         int(xs) + int(ys)*ti.BufferWidth,
+				Width:                xe - xs,
+				Height:               ye - ys,
+				stats:                st,
 			}
-			if chunk.Width > chunkSizef {
-				chunk.Width = chunkSizef
+			if ctx.Err() != nil {
+				break
 			}
-
-			r.renderChunk(chunk)
-			w0 += w0xStep
-			w1 += w1xStep
-			w2 += w2xStep
-			boffs += chunkSize
+			r.submit(ctx, chunk, int(xs), int(ys), &wg, &tm)
 		}
-		w0o += w0yStep
-		w1o += w1yStep
-		w2o += w2yStep
-		boffso += ti.BufferWidth * chunkSize
 	}
-	wg.Wait()
+	r.wait(&wg, &tm)
+	return ctx.Err()
 }
 
 func (c TriangleChunk) Process() {
-	defer c.WG.Done()
-
 	maxX := c.Xo + c.Width
 	maxY := c.Yo + c.Height
 	so := TriangleShaderOpts{
 		TriangleRasterStatic: c.TriangleRasterStatic,
 	}
+	so.Lights = chunkLights(c.Lights, c.Xo, c.Yo, c.Width, c.Height)
+	var cs *chunkStats
+	if c.stats != nil {
+		cs = &chunkStats{lights: countingLights{Lights: so.Lights}}
+		so.Lights = &cs.lights
+		so.Blend.written = &cs.written
+	}
+	so.Lights, so.Indexizer = rampShading(so.Lights, so.Indexizer)
 
 	cnt := 0
 	for y := c.Yo; y < maxY; y++ {
-		w0, w1, w2 := c.W0o, c.W1o, c.W2o
+		dy := y - c.oy
+		r0, r1, r2 := c.w0o+dy*c.B12, c.w1o+dy*c.B20, c.w2o+dy*c.B01
 		boffs := c.BufferOffset
 		for x := c.Xo; x < maxX; x++ {
+			dx := x - c.ox
+			w0, w1, w2 := r0+dx*c.A12, r1+dx*c.A20, r2+dx*c.A01
 			if w0 >= 0 && w1 >= 0 && w2 >= 0 {
 				so.BufferOffset = boffs
 				so.W0 = w0 * c.InvArea
@@ -236,18 +256,15 @@ func (c TriangleChunk) Process() {
 				so.X = x
 				so.Y = y
 				c.Shader(&so)
+				cnt++
 			}
-			w0 += c.A12
-			w1 += c.A20
-			w2 += c.A01
 			boffs++
-			cnt++
 		}
-		c.W0o += c.B12
-		c.W1o += c.B20
-		c.W2o += c.B01
 		c.BufferOffset += c.BufferWidth
 	}
+	if cs != nil {
+		cs.flush(c.stats, cnt)
+	}
 }
 
 // edgeFunc calculates triangle edge function.